@@ -0,0 +1,266 @@
+package dojoBuilder
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archive formats
+const (
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+var (
+	archiveExcludeFunc ExcludeFunc = func(path string, f os.FileInfo) (bool, error) {
+		return false, nil
+	}
+
+	// DefaultArchiveExcludeFunc skips source maps, uncompressed and consoleStripped js files
+	DefaultArchiveExcludeFunc = func(path string, f os.FileInfo) (bool, error) {
+		var skippedFilesPatterns []string = []string{`.*\.js\.(uncompressed|consoleStripped)\.js$`, `.*\.map$`}
+
+		if f.IsDir() {
+			return false, nil
+		}
+
+		return IsMatchSliceMember(skippedFilesPatterns, path)
+	}
+)
+
+func SetArchiveExcludeFunc(exFunc ExcludeFunc) { archiveExcludeFunc = exFunc }
+
+// ArchiveFormat returns the archive format matching the extension of name
+func ArchiveFormat(name string) (string, error) {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip, nil
+	}
+
+	return "", fmt.Errorf("Unknown archive format for %s", name)
+}
+
+// Archive writes the files of DestDir into the archive name using format
+// (ArchiveTarGz or ArchiveZip). If format is empty, it's guessed from name.
+// A manifest of the archived files, tagged with the build id and the commit
+// DestDir was output by, is added as ManifestFileName. DestDir is locked
+// while it is archived, see LockTimeout.
+func (c *Config) Archive(name, format string) (err error) {
+	if !c.locked {
+		unlock, lerr := c.lockDestDir()
+		if lerr != nil {
+			return lerr
+		}
+		defer unlock()
+	}
+
+	if format == "" {
+		if format, err = ArchiveFormat(name); err != nil {
+			return
+		}
+	}
+
+	if format != ArchiveTarGz && format != ArchiveZip {
+		return fmt.Errorf("Unknown archive format '%s'", format)
+	}
+
+	exclude, err := namedExcludeFunc(c.ArchiveExcludes, archiveExcludeFunc, nil)
+	if err != nil {
+		return
+	}
+
+	archivePath, err := filepath.Abs(name)
+	if err != nil {
+		return
+	}
+
+	out, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(archivePath)
+		}
+	}()
+
+	var aw archiveWriter
+	if format == ArchiveZip {
+		aw = &zipArchiveWriter{zip.NewWriter(out)}
+	} else {
+		gw := gzip.NewWriter(out)
+		aw = &tarArchiveWriter{gw, tar.NewWriter(gw)}
+	}
+
+	m := &Manifest{Commit: c.builtCommit(), Files: make(map[string]string)}
+	if info, err := ReadBuildInfo(c.DestDir); err == nil {
+		m.BuildID = info.BuildID
+	}
+
+	err = filepath.Walk(c.DestDir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if path == c.DestDir || path == archivePath {
+			return nil
+		}
+
+		if skip, err := exclude(path, f); err != nil {
+			return err
+		} else if skip {
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Follow symlinks so the archive is usable on its own
+		if f.Mode()&os.ModeSymlink != 0 {
+			if f, err = os.Stat(path); err != nil {
+				return err
+			}
+		}
+
+		rel, err := filepath.Rel(c.DestDir, path)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestFileName || rel == LockFileName {
+			return nil
+		}
+
+		if !f.IsDir() {
+			if m.Files[rel], err = fileSHA256(path); err != nil {
+				return err
+			}
+		}
+
+		return aw.add(rel, path, f)
+	})
+
+	if err == nil {
+		var b []byte
+		if b, err = json.MarshalIndent(m, "", "  "); err == nil {
+			err = aw.addBytes(ManifestFileName, b)
+		}
+	}
+
+	if cerr := aw.Close(); err == nil {
+		err = cerr
+	}
+
+	return
+}
+
+type archiveWriter interface {
+	add(name, path string, f os.FileInfo) error
+	addBytes(name string, b []byte) error
+	Close() error
+}
+
+type tarArchiveWriter struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+func (w *tarArchiveWriter) add(name, path string, f os.FileInfo) (err error) {
+	h, err := tar.FileInfoHeader(f, "")
+	if err != nil {
+		return
+	}
+
+	h.Name = name
+	if f.IsDir() {
+		h.Name += "/"
+	}
+
+	if err = w.tw.WriteHeader(h); err != nil || f.IsDir() {
+		return
+	}
+
+	return copyInto(w.tw, path)
+}
+
+func (w *tarArchiveWriter) addBytes(name string, b []byte) (err error) {
+	h := &tar.Header{Name: name, Mode: 0664, Size: int64(len(b)), ModTime: time.Now()}
+
+	if err = w.tw.WriteHeader(h); err != nil {
+		return
+	}
+
+	_, err = w.tw.Write(b)
+
+	return
+}
+
+func (w *tarArchiveWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+
+	return w.gw.Close()
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipArchiveWriter) add(name, path string, f os.FileInfo) (err error) {
+	h, err := zip.FileInfoHeader(f)
+	if err != nil {
+		return
+	}
+
+	h.Name = name
+	if f.IsDir() {
+		h.Name += "/"
+	} else {
+		h.Method = zip.Deflate
+	}
+
+	fw, err := w.zw.CreateHeader(h)
+	if err != nil || f.IsDir() {
+		return
+	}
+
+	return copyInto(fw, path)
+}
+
+func (w *zipArchiveWriter) addBytes(name string, b []byte) (err error) {
+	fw, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return
+	}
+
+	_, err = fw.Write(b)
+
+	return
+}
+
+func (w *zipArchiveWriter) Close() error { return w.zw.Close() }
+
+func copyInto(w io.Writer, path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+
+	return
+}
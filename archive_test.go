@@ -0,0 +1,46 @@
+package dojoBuilder_test
+
+import (
+	"archive/zip"
+	"path/filepath"
+	"testing"
+
+	"github.com/tbaud0n/dojoBuilder"
+	"github.com/tbaud0n/dojoBuilder/buildertest"
+)
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestConfig(t, dir, "a")
+
+	fb := &buildertest.FakeBuilder{}
+	fb.Install()
+	defer fb.Uninstall()
+
+	if _, err := c.Build(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "release.zip")
+	if err := c.Archive(path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]bool)
+	for _, f := range zr.File {
+		files[f.Name] = true
+	}
+
+	if files[dojoBuilder.LockFileName] {
+		t.Errorf("The archive contains the lock file %s", dojoBuilder.LockFileName)
+	}
+	if !files[dojoBuilder.ManifestFileName] {
+		t.Errorf("The archive has no manifest: %v", files)
+	}
+}
@@ -0,0 +1,160 @@
+package dojoBuilder
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// Actions of the audit events
+const (
+	AuditBuild    = "build"
+	AuditDeploy   = "deploy"
+	AuditRollback = "rollback"
+	AuditPromote  = "promote"
+)
+
+// AuditEvent records who changed the front-end, when and how
+type AuditEvent struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"` // AuditBuild, AuditDeploy, AuditRollback or AuditPromote
+	User    string    `json:"user"`   // User running the build
+	Name    string    `json:"name"`   // Build config name
+	BuildID string    `json:"buildId,omitempty"`
+	GitRef  string    `json:"gitRef,omitempty"` // GitRef built, if any
+	Commit  string    `json:"commit,omitempty"` // Commit built, if known, see Config.VCS
+	Branch  string    `json:"branch,omitempty"` // VCS branch built, if known
+	Target  string    `json:"target,omitempty"` // Deploy target, or promoted release
+	Error   string    `json:"error,omitempty"`
+}
+
+// AuditSink receives the audit events, e.g. to forward them to a central log
+type AuditSink interface {
+	Record(e AuditEvent) error
+}
+
+// AuditFile is an AuditSink appending the events to the file at its path, one
+// JSON event per line. The file is only ever appended to.
+type AuditFile string
+
+var auditFileMu sync.Mutex
+
+func (f AuditFile) Record(e AuditEvent) (err error) {
+	auditFileMu.Lock()
+	defer auditFileMu.Unlock()
+
+	file, err := os.OpenFile(string(f), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return json.NewEncoder(file).Encode(e)
+}
+
+// ReadAuditLog reads the audit file at path
+func ReadAuditLog(path string) (events []AuditEvent, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		var e AuditEvent
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, scanner.Err()
+}
+
+// auditUser returns the name of the user running the build
+func auditUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+
+	return "unknown"
+}
+
+// audit records e into the AuditLog and the AuditSink of c, completing its
+// time, user, git ref and branch
+func (c *Config) audit(e AuditEvent) (err error) {
+	if c.AuditLog == "" && c.AuditSink == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	e.User = auditUser()
+	e.GitRef = c.GitRef
+	e.Branch = c.revision.Branch
+
+	if c.AuditLog != "" {
+		err = AuditFile(c.AuditLog).Record(e)
+	}
+
+	if c.AuditSink != nil {
+		if serr := c.AuditSink.Record(e); err == nil {
+			err = serr
+		}
+	}
+
+	return
+}
+
+// auditResults records the builds of results and their deployments
+func (c *Config) auditResults(results []*BuildResult) error {
+	for _, r := range results {
+		e := AuditEvent{Action: AuditBuild, Name: r.Name, BuildID: r.BuildID, Commit: r.Commit}
+		if r.Err != nil {
+			e.Error = r.Err.Error()
+		}
+
+		if err := c.audit(e); err != nil {
+			return err
+		}
+
+		for _, d := range r.Deployments {
+			de := AuditEvent{Action: AuditDeploy, Name: r.Name, BuildID: r.BuildID, Commit: r.Commit, Target: d.Target}
+			if d.Err != nil {
+				de.Error = d.Err.Error()
+			}
+
+			if err := c.audit(de); err != nil {
+				return err
+			}
+
+			if !d.RolledBack && d.RollbackErr == nil {
+				continue
+			}
+
+			de.Action, de.Error = AuditRollback, ""
+			if d.RollbackErr != nil {
+				de.Error = d.RollbackErr.Error()
+			}
+
+			if err := c.audit(de); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package dojoBuilder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BrowserVerifyTimeout is the maximum duration of a headless browser check
+var BrowserVerifyTimeout = time.Minute
+
+// Browser executables looked for when Config.ChromeBin is empty
+var chromeBins = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+const verifyPagePath = "/dojoBuilderVerify.html"
+
+const verifyPageTemplate = `<!doctype html>
+<html>
+<head><meta charset="utf-8"></head>
+<body>
+  <script type="text/javascript">
+    var dojoConfig = {{.DojoConfig}}, paths = {{.Paths}};
+    dojoConfig.async = true;
+    dojoConfig.paths = dojoConfig.paths || {};
+    for(var mid in paths){ dojoConfig.paths[mid] = paths[mid]; }
+  </script>
+  <script type="text/javascript" src="{{.Boot}}"></script>
+  <script type="text/javascript">
+    (function(){
+      var errors = [], done = false;
+      function report(status){
+        if(done){ return; }
+        done = true;
+        document.body.setAttribute("data-dojobuilder-result", JSON.stringify({status: status, errors: errors}));
+      }
+      window.onerror = function(msg){ errors.push(String(msg)); };
+      if(typeof require === "undefined"){
+        errors.push("The boot layer did not define require");
+        report("error");
+        return;
+      }
+      require.on("error", function(e){
+        errors.push(e.message + (e.info ? " " + e.info.join(" ") : ""));
+        report("error");
+      });
+      require({{.Layers}}, function(){ report(errors.length ? "error" : "ok"); });
+      setTimeout(function(){ report("timeout"); }, 10000);
+    })();
+  </script>
+</body>
+</html>`
+
+var verifyResultRegexp = regexp.MustCompile(`data-dojobuilder-result="([^"]*)"`)
+
+type verifyResult struct {
+	Status string   `json:"status"`
+	Errors []string `json:"errors"`
+}
+
+// verifyLayers returns the url of the boot layer of bc, the build config
+// name, the other layers and the loader paths of the ones out of their
+// package, e.g. moved by the LayerDests. The urls are absolute paths of the
+// files of DestDir.
+func (c *Config) verifyLayers(name string, bc BuildConfig) (boot string, layers []string, paths map[string]string, err error) {
+	res := NewResolver(c.DestDir, releasePackages(bc.Packages), nil)
+
+	url := func(p string) (string, error) {
+		rel, err := filepath.Rel(c.DestDir, p)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return "", errors.New("The layer file " + p + " is not in DestDir")
+		}
+
+		return "/" + urlPath(filepath.ToSlash(rel)), nil
+	}
+
+	boot = "/dojo/dojo.js"
+	paths = make(map[string]string)
+
+	for mid, l := range bc.Layers {
+		p, err := c.builtLayer(name, bc, mid)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		u, err := url(p)
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		if l.Boot {
+			boot = u
+			continue
+		}
+
+		layers = append(layers, mid)
+		if pp, err := res.Path(mid); err != nil || pp != p {
+			paths[mid] = strings.TrimSuffix(u, ".js")
+		}
+	}
+	sort.Strings(layers)
+
+	return
+}
+
+// VerifyInBrowser loads the boot layer of the build config name from DestDir
+// in a headless Chrome, requires all the other layers and fails if the AMD
+// loader reports any error.
+func (c *Config) VerifyInBrowser(name string) (err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return configNotFound(name)
+	}
+
+	bin, err := c.chromeBin()
+	if err != nil {
+		return
+	}
+
+	boot, layers, paths, err := c.verifyLayers(name, bc)
+	if err != nil {
+		return
+	}
+
+	dojoConfig := template.JS("{}")
+	if c.DojoConfigRelPath != "" {
+		if dojoConfig, err = c.DojoConfig(name); err != nil {
+			return
+		}
+	}
+
+	layersJSON, err := json.Marshal(layers)
+	if err != nil {
+		return
+	}
+
+	pathsJSON, err := json.Marshal(paths)
+	if err != nil {
+		return
+	}
+
+	page := template.Must(template.New("verify").Parse(verifyPageTemplate))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(verifyPagePath, func(w http.ResponseWriter, r *http.Request) {
+		page.Execute(w, map[string]interface{}{
+			"DojoConfig": dojoConfig,
+			"Boot":       boot,
+			"Layers":     template.JS(layersJSON),
+			"Paths":      template.JS(pathsJSON),
+		})
+	})
+	mux.Handle("/", http.FileServer(http.Dir(c.DestDir)))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return
+	}
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), BrowserVerifyTimeout)
+	defer cancel()
+
+	url := "http://" + l.Addr().String() + verifyPagePath
+	out, err := exec.CommandContext(ctx, bin, "--headless", "--disable-gpu", "--no-sandbox",
+		"--virtual-time-budget=15000", "--dump-dom", url).Output()
+	if err != nil {
+		return fmt.Errorf("Headless browser failed: %s", err)
+	}
+
+	m := verifyResultRegexp.FindSubmatch(out)
+	if m == nil {
+		return errors.New("The browser check page did not report any result")
+	}
+
+	var r verifyResult
+	if err = json.Unmarshal([]byte(html.UnescapeString(string(m[1]))), &r); err != nil {
+		return
+	}
+
+	if r.Status != "ok" {
+		return fmt.Errorf("Layers failed to load in the browser (%s): %s", r.Status, strings.Join(r.Errors, "; "))
+	}
+
+	fmt.Fprintf(c.stdout(), "Layers of %s build loaded in the browser without error\n", name)
+
+	return
+}
+
+func (c *Config) chromeBin() (string, error) {
+	if c.ChromeBin != "" {
+		return c.ChromeBin, nil
+	}
+
+	for _, b := range chromeBins {
+		if p, err := exec.LookPath(b); err == nil {
+			return p, nil
+		}
+	}
+
+	return "", errors.New("No headless browser found, set Config.ChromeBin")
+}
@@ -1,22 +1,76 @@
 package dojoBuilder
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
-	"text/template"
+	"time"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
+)
+
+const (
+	ReleaseBuildMode = "release" // Build with the dojo builder
+	FastBuildMode    = "fast"    // Development build made with esbuild
 )
 
-const profileTemplate = `var profile = {{.}};`
+// NoLayerOptimize is the LayerOptimize disabling the optimization of the
+// layers, written as false in the profile: an empty LayerOptimize lets the
+// dojo builder use shrinksafe.
+const NoLayerOptimize = "none"
 
 type BuildConfig struct {
-	RemoveUncompressed    bool `json:"removeUncompressed,omitempty"` // Remove uncompressed js files after build
-	RemoveConsoleStripped bool `json:"removeConsoleStripped,omitempty"`
+	RemoveUncompressed     bool              `json:"removeUncompressed,omitempty"`    // Remove uncompressed js files after build
+	RemoveConsoleStripped  bool              `json:"removeConsoleStripped,omitempty"` // Remove consoleStripped js files after build
+	Transpiler             *Transpiler       `json:"-"`                               // Transpile some packages before building (optional)
+	Mode                   string            `json:"-"`                               // ReleaseBuildMode (default) or FastBuildMode
+	ReportInterns          bool              `json:"-"`                               // Print the templates interned into layers after the build
+	Layout                 string            `json:"-"`                               // Layout of the release in DestDir, StandardLayout (default), FlatLayout or VersionedLayout
+	Retention              *Retention        `json:"-"`                               // Versioned releases kept in DestDir after a successful build of the VersionedLayout (optional, default all)
+	VCSReleaseNames        bool              `json:"-"`                               // Prefix the VersionedLayout release dirs with the VCS version of the sources, see Config.VCS
+	Canary                 bool              `json:"-"`                               // Output the VersionedLayout release as the canary of the current one, served by CanaryLayoutFuncs until Promote
+	Normalize              bool              `json:"-"`                               // Normalize the line endings and build report times of the release, and its file times to SOURCE_DATE_EPOCH if set, so identical sources give identical releases
+	ReportExcluded         bool              `json:"-"`                               // Print the paths of the release skipped by the build exclude funcs
+	MaxLayerGrowth         float64           `json:"-"`                               // Max growth (percent) of the layers gzip size compared to Config.SizeBaseline (optional)
+	FailOnLayerGrowth      bool              `json:"-"`                               // Fail the build when a layer grows more than MaxLayerGrowth instead of warning
+	VerifyInBrowser        bool              `json:"-"`                               // Load the built layers in a headless browser after the build
+	SmokeTests             []string          `json:"-"`                               // Node scripts (relative to SrcDir) run against the release after the build
+	Tests                  *TestSuite        `json:"-"`                               // Test suite run after the build (optional)
+	Lint                   *Lint             `json:"-"`                               // Lint stage run before the build (optional)
+	ReportI18n             bool              `json:"-"`                               // Print the nls bundle translations missing or having extra keys before the build
+	Themes                 []Theme           `json:"-"`                               // Themes built into their own output directory after the build (optional)
+	DisableShims           bool              `json:"-"`                               // Do not apply the PackageShims to the packages
+	SuggestLayerSplits     bool              `json:"-"`                               // Print the modules worth moving out of the boot layers after the build
+	ReportDeadCSS          bool              `json:"-"`                               // Print the CSS selectors likely used by no template, layer or HTMLFiles after the build
+	ReportLayerReferences  bool              `json:"-"`                               // Warn about the references of the HTMLFiles to layers or scripts which are not built, and about the layers they don't reference, after the build
+	HTMLFiles              []string          `json:"-"`                               // HTML files and page templates of the application (relative to SrcDir) scanned by ReportDeadCSS and ReportLayerReferences (optional)
+	XDomainOnly            bool              `json:"-"`                               // Remove from the release the modules having a cross-domain version (.xd.js) built by the XDomainLoader
+	Deploy                 []DeployTarget    `json:"-"`                               // Targets the release is deployed to, in parallel, after a successful build (optional)
+	DeployAllOrNothing     bool              `json:"-"`                               // Roll back the deployments to the other Deploy targets when one fails
+	VendorLayer            string            `json:"-"`                               // Layer of Layers the toolkit (dojo, dijit, dojox) modules used by the other layers are moved to, so that toolkit upgrades leave the application layers unchanged (optional)
+	CacheBust              bool              `json:"-"`                               // Add the "?v=<hash of the release>" query string of CacheBust to the urls of LayoutFuncs and of the preload manifest, for deployments which cannot use the VersionedLayout
+	LayerDests             map[string]string `json:"-"`                               // Layer module id => destination of the layer and its source map, relative to DestDir or absolute, keeping the layer file name when ending with "/" (optional)
+	InlineResourcesMaxSize int64             `json:"-"`                               // Images and fonts of at most this size (bytes) referenced by the themes and the stylesheets of the layers are inlined as data URIs (optional, 0 disables)
+	PreloadManifestFile    string            `json:"-"`                               // Path, relative to DestDir, of the preload manifest written after the build (optional)
+	PrecacheManifestFile   string            `json:"-"`                               // Path, relative to DestDir, of the Workbox precache manifest written after the build (optional)
+	PrecacheBaseURL        string            `json:"-"`                               // Url of DestDir prefixing the precache manifest urls (optional)
+	PrecachePatterns       []RegExp          `json:"-"`                               // Patterns of the paths (relative to DestDir) to precache (optional, default all)
+	WriteMetadata          bool              `json:"-"`                               // Write the metadata sidecar (ETag, mtime, content type) of DestDir after the build
+	ContentTypes           ContentTypes      `json:"-"`                               // Content types and Cache-Control of the metadata by extension, e.g. ".mjs" (optional, see DefaultContentTypes)
+	LicenseFile            string            `json:"-"`                               // Path, relative to DestDir, of the license headers of the layer modules written after the build (optional)
+	StripLicenses          bool              `json:"-"`                               // Remove the license comments kept by the optimizer from the layers, for use with LicenseFile
+	ProfileTemplate        string            `json:"-"`                               // text/template of the profile file, executed with a ProfileData (optional, default DefaultProfileTemplate)
+
+	ExtraProperties map[string]interface{} `json:"-"` // Profile properties not covered by BuildConfig, JS and RegExp values are written as is (optional)
 
 	BasePath    string           `json:"basePath"`
 	ReleaseDir  string           `json:"releaseDir"`
@@ -25,19 +79,43 @@ type BuildConfig struct {
 	Packages    []Package        `json:"packages"`
 	Layers      map[string]Layer `json:"layers"`
 
-	LayerOptimize     string             `json:"layerOptimize,omitempty"`
+	Map          map[string]map[string]string `json:"map,omitempty"`        // Loader module map
+	Aliases      []Alias                      `json:"aliases,omitempty"`    // Module id aliases
+	Transforms   map[string]Transform         `json:"transforms,omitempty"` // Custom build transforms by name
+	Plugins      map[string]string            `json:"plugins,omitempty"`    // Plugin module id => build-time plugin resolver module id
+	Replacements map[string]string            `json:"-"`                    // Module id => replacement module id, js file or "" for an empty module
+
+	Defines       map[string]interface{} `json:"-"` // Build-time constants (API urls, feature flags...) returned by the DefinesModule, the boolean ones being has-features as well (optional)
+	DefinesModule string                 `json:"-"` // Module id of the Defines module (optional, default DefaultDefinesModule)
+
+	LayerOptimize     string             `json:"layerOptimize,omitempty"` // Optimizer of the layers, NoLayerOptimize for none (optional, dojo default is shrinksafe)
 	Optimize          string             `json:"optimize,omitempty"`
+	OptimizeOptions   *OptimizeOptions   `json:"optimizeOptions,omitempty"`
 	CssOptimize       string             `json:"cssOptimize,omitempty"`
 	Mini              bool               `json:"mini,omitempty"`
+	InternStrings     *bool              `json:"internStrings,omitempty"`  // Intern templates into layers (dojo default is true)
+	InternSkipList    []RegExp           `json:"internSkipList,omitempty"` // Patterns of the template ids which must not be interned
 	StripConsole      string             `json:"stripConsole,omitempty"`
 	SelectorEngine    string             `json:"selectorEngine,omitempty"`
 	StaticHasFeatures map[string]Feature `json:"staticHasFeatures,omitempty"`
-	UseSourceMaps     bool               `json:"useSourceMaps"` // Build generate source maps
+	UseSourceMaps     bool               `json:"useSourceMaps"`        // Build generate source maps
+	LocaleList        []string           `json:"localeList,omitempty"` // Locales of the nls bundles flattened into layers
+	Loader            string             `json:"loader,omitempty"`     // XDomainLoader for a cross-domain build of legacy modules (dojo < 1.7)
+	XdDojoPath        string             `json:"xdDojoPath,omitempty"` // Url of the dojo directory the cross-domain modules are loaded from, e.g. on a CDN (optional)
+
+	MaxOptimizationProcesses int `json:"maxOptimizationProcesses,omitempty"` // Max number of parallel optimizer processes (dojo default is the number of CPUs)
 }
 
 type Package struct {
-	Name     string `json:"name"`
-	Location string `json:"location"`
+	Name         string            `json:"name"`
+	Location     string            `json:"location"`               // Directory of the package, relative to SrcDir or absolute for a package outside SrcDir
+	Main         string            `json:"main,omitempty"`         // Module loaded for the package id itself (default "main")
+	DestLocation string            `json:"destLocation,omitempty"` // Location of the package in the release (default Location, Name for a package outside SrcDir)
+	PackageMap   map[string]string `json:"packageMap,omitempty"`   // Package name => name of the package to use instead within this package
+	Trees        []ResourceDir     `json:"trees,omitempty"`        // Directory trees of the package resources
+	Dirs         []ResourceDir     `json:"dirs,omitempty"`         // Directories (non recursive) of the package resources
+	Files        []ResourceDir     `json:"files,omitempty"`        // Single files of the package resources
+	ResourceTags *ResourceTags     `json:"resourceTags,omitempty"` // Resource tags (amd, copyOnly, test, miniExclude)
 }
 
 type Layer struct {
@@ -45,6 +123,9 @@ type Layer struct {
 	CustomBase bool     `json:"customBase"`
 	Include    []string `json:"include,omitempty"`
 	Exclude    []string `json:"exclude,omitempty"`
+
+	When       map[string]Feature `json:"-"` // Has-features the build config must statically set to these values for the layer to be built (optional)
+	Conditions []LayerCondition   `json:"-"` // Modules included or excluded according to the has-features of the build config (optional)
 }
 
 type Feature bool
@@ -57,6 +138,24 @@ func (f Feature) MarshalJSON() ([]byte, error) {
 	return json.Marshal(v)
 }
 
+func (f *Feature) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case bool:
+		*f = Feature(t)
+	case float64:
+		*f = t != 0
+	default:
+		return fmt.Errorf("Invalid has feature value %s", b)
+	}
+
+	return nil
+}
+
 var (
 	buildExcludeFunc ExcludeFunc = func(path string, f os.FileInfo) (bool, error) {
 		return false, nil
@@ -78,43 +177,176 @@ var (
 
 func SetBuildExcludeFunc(exFunc ExcludeFunc) { buildExcludeFunc = exFunc }
 
-func (c *Config) generateBuildProfile(name string) (profileFullPath string, err error) {
+// BuildFunc runs the build of the profile generated at profilePath from bc.
+// The release has to be output in bc.ReleaseDir.
+type BuildFunc func(c *Config, bc BuildConfig, profilePath string) error
+
+// DefaultBuildFunc runs the dojo build script of SrcDir
+func DefaultBuildFunc(c *Config, bc BuildConfig, profilePath string) error {
+	return c.executeBuildProfile(bc, profilePath)
+}
+
+var buildFunc BuildFunc = DefaultBuildFunc
+
+// SetBuildFunc replaces the dojo builder, mostly for testing purpose
+func SetBuildFunc(f BuildFunc) { buildFunc = f }
+
+// resolveBuildConfig returns the build config name as written into its
+// profile: defaults applied, packages and paths resolved. The profile JSON
+// is j. The warnings about the options the toolkit doesn't support are left
+// to the caller to print.
+func (c *Config) resolveBuildConfig(name string) (bc BuildConfig, j []byte, warnings []string, err error) {
 	bc, ok := c.BuildConfigs[name]
 	if !ok {
-		return "", errors.New("No build config found with name '" + name + "'")
+		return bc, nil, nil, configNotFound(name)
 	}
 
 	if bc.Action == "" {
 		bc.Action = "release"
 	}
 
-	profilePath := c.SrcDir + "/profiles/"
-	os.MkdirAll(profilePath, 0754)
+	os.MkdirAll(c.profilesDir(), 0754)
+
+	// The profile paths are relative to its directory
+	if bc.BasePath, err = filepath.Rel(c.profilesDir(), c.SrcDir); err != nil {
+		return
+	}
+	bc.BasePath = filepath.ToSlash(bc.BasePath)
+
+	bc.ReleaseDir = c.releaseDir(name)
+
+	if v, err := c.DojoVersion(); err == nil {
+		warnings = applyToolkitCapabilities(&bc, v)
+	}
+
+	if err = validatePackages(bc); err != nil {
+		return
+	}
+
+	if err = validateTransforms(bc); err != nil {
+		return
+	}
+
+	if bc.VendorLayer != "" {
+		if err = c.applyVendorLayer(&bc); err != nil {
+			return
+		}
+	}
+
+	bc.Packages = c.sourcePackages(bc.Packages)
+
+	if !bc.DisableShims {
+		bc.Packages = applyPackageShims(c.stdout(), c.SrcDir, bc.Packages)
+	}
+
+	bc.Packages = resolveResources(bc.Packages, c.SrcDir, bc.ReleaseDir)
+
+	if bc.Packages, err = profilePackages(c.SrcDir, bc.Packages); err != nil {
+		return
+	}
+
+	if err = c.applyReplacements(name, &bc); err != nil {
+		return
+	}
 
-	profileFullPath = profilePath + name + ".profile.js"
+	if err = c.applyDefines(name, &bc); err != nil {
+		return
+	}
+
+	if bc.OptimizeOptions != nil {
+		if bc.OptimizeOptions, err = c.resolveOptimizeOptions(bc.OptimizeOptions); err != nil {
+			return
+		}
+	}
 
-	bc.BasePath = ".."
+	if j, err = json.Marshal(bc); err != nil {
+		return
+	}
 
-	bc.ReleaseDir = c.DestDir + "/dojoBuilderTMP"
+	if bc.LayerOptimize == NoLayerOptimize {
+		if j, err = mergeProfileProperties(j, map[string]interface{}{"layerOptimize": false}); err != nil {
+			return
+		}
+	}
 
-	j, err := json.Marshal(bc)
+	if jobs := transformJobs(bc); jobs != nil {
+		if j, err = mergeProfileProperties(j, map[string]interface{}{"transformJobs": jobs}); err != nil {
+			return
+		}
+	}
+
+	j, err = mergeProfileProperties(j, bc.ExtraProperties)
+
+	return
+}
+
+func (c *Config) generateBuildProfile(name string) (bc BuildConfig, profileFullPath string, err error) {
+	if c.profile != nil {
+		return c.writeExternalProfile(name)
+	}
+
+	bc, j, warnings, err := c.resolveBuildConfig(name)
+	if err != nil {
+		return bc, "", err
+	}
+
+	for _, w := range append(warnings, customBaseWarnings(bc)...) {
+		fmt.Fprintf(c.stdout(), "Warning: %s\n", w)
+	}
+
+	profileFullPath = c.workPath(c.profilesDir(), name, "") + ".profile.js"
+
+	t, err := parseProfileTemplate(bc)
 	if err != nil {
-		return "", err
+		return bc, "", err
+	}
+
+	data, err := newProfileData(name, bc, j)
+	if err != nil {
+		return bc, "", err
 	}
 
 	f, err := os.OpenFile(profileFullPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
 	if err != nil {
-		return "", err
+		return bc, "", err
 	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
 
-	t := template.Must(template.New("profileTemplate").Parse(profileTemplate))
-	err = t.Execute(f, string(j))
+	err = t.Execute(f, data)
 
-	return profileFullPath, err
+	return bc, profileFullPath, err
 }
 
 func (c *Config) build(names []string) (err error) {
-	var profilePath string
+	_, err = c.Build(names)
+	return
+}
+
+// Build builds the build configs names (all if empty) into DestDir and
+// returns the result of each build. It stops at the first failing build.
+// DestDir is locked during the build, see LockTimeout.
+func (c *Config) Build(names []string) (results []*BuildResult, err error) {
+	if !c.locked {
+		unlock, lerr := c.lockDestDir()
+		if lerr != nil {
+			return nil, lerr
+		}
+		defer unlock()
+
+		lc := *c
+		lc.locked = true
+		c = &lc
+	}
+
+	if c.buildID == "" {
+		rc := *c
+		rc.buildID = newBuildID()
+		c = &rc
+	}
 
 	if len(names) == 0 {
 		for n, _ := range c.BuildConfigs {
@@ -122,82 +354,839 @@ func (c *Config) build(names []string) (err error) {
 		}
 	}
 
+	if len(names) > 1 {
+		defer func() {
+			PrintBuildSummary(c.stdout(), BuildSummary(names, results))
+		}()
+	}
+
+	if c.JUnitReport != "" {
+		defer func() {
+			if jerr := WriteJUnitReportFile(c.JUnitReport, results); err == nil {
+				err = jerr
+			}
+		}()
+	}
+
+	if c.History != "" {
+		defer func() {
+			if herr := appendHistory(c.History, results); err == nil {
+				err = herr
+			}
+		}()
+	}
+
+	defer func() {
+		if aerr := c.auditResults(results); err == nil {
+			err = aerr
+		}
+	}()
+
+	if err = c.checkSpace(); err != nil {
+		return
+	}
+
+	src, commit := c, ""
+	if c.GitRef != "" {
+		// The checkout is already isolated from the sources
+		var dir string
+		if src, commit, dir, err = c.checkoutGitRef(); err != nil {
+			return
+		}
+		defer os.RemoveAll(dir)
+	} else if c.Snapshot != "" {
+		if src, err = c.snapshot(); err != nil {
+			return
+		}
+		defer os.RemoveAll(src.SrcDir)
+	}
+
+	if err = src.verifyToolkit(); err != nil {
+		return
+	}
+
+	// The GitRef checkout is the revision built
+	vc := c
+	if c.GitRef != "" {
+		vc = src
+	}
+	rc := *c
+	if rc.revision = vc.vcsInfo(); commit != "" {
+		rc.revision.Commit = commit
+	}
+	c = &rc
+
 	for _, n := range names {
-		fmt.Printf("Generating %s build\n", n)
+		if c.canceled() {
+			return results, ErrBuildCanceled
+		}
+
+		r := &BuildResult{Name: n, BuildID: c.buildID, Commit: c.revision.Commit}
+		results = append(results, r)
+
+		start := time.Now()
+		err = c.buildConfig(src, n, r)
+		r.Duration = time.Since(start)
 
-		profilePath, err = c.generateBuildProfile(n)
 		if err != nil {
+			r.Err = err
 			return
 		}
+	}
+
+	info := &BuildInfo{BuildID: c.buildID, Time: time.Now(), Commit: c.revision.Commit, Version: c.revision.Version, Branch: c.revision.Branch, Builds: names}
+	err = info.write(c.DestDir)
+
+	return
+}
 
-		if err = c.executeBuildProfile(profilePath); err != nil {
+// buildRelease builds the build config name, bc, from the sources of c into
+// its release directory
+func (c *Config) buildRelease(name string, bc BuildConfig, r *BuildResult) (err error) {
+	sc := c
+	if bc.Transpiler != nil {
+		if sc, err = c.transpile(name, bc, bc.Transpiler); err != nil {
 			return
 		}
+	}
 
-		bc, _ := c.BuildConfigs[n]
-		bc.ReleaseDir = c.DestDir + "/dojoBuilderTMP"
+	if bc.Mode == FastBuildMode {
+		err = sc.executeFastBuild(name)
+	} else {
+		pbc, profilePath, perr := sc.generateBuildProfile(name)
+		if perr != nil && !errors.Is(perr, ErrConfigNotFound) {
+			err = profileError{perr}
+		} else if err = perr; err == nil {
+			rc := *sc
+			rc.result = r
+			err = rc.cachedBuild(pbc, profilePath)
+		}
+		sc.removeProfile(name, profilePath)
+	}
 
-		err = filepath.Walk(bc.ReleaseDir, func(path string, f os.FileInfo, err error) (_err error) {
-			if path == bc.ReleaseDir {
-				return
-			}
+	if sc != c {
+		os.RemoveAll(sc.SrcDir)
+	}
 
-			isDir := f.IsDir()
-			dest := c.DestDir + path[len(bc.ReleaseDir):]
+	return
+}
 
-			if skip, err := buildExcludeFunc(path, f); err != nil {
-				return err
-			} else if skip {
-				if isDir {
-					return filepath.SkipDir
+// buildConfig builds the build config name from the sources of src
+func (c *Config) buildConfig(src *Config, name string, r *BuildResult) (err error) {
+	fmt.Fprintf(c.stdout(), "Generating %s build (%s)\n", name, c.buildID)
+
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return configNotFound(name)
+	}
+
+	if err = validateCanary(bc); err != nil {
+		return
+	}
+
+	// The next steps only see the layers of the has-features of bc
+	if rc, skipped := c.withLayerConditions(name); rc != c {
+		for _, mid := range skipped {
+			fmt.Fprintf(c.stdout(), "Layer %s of %s skipped by its has-features condition\n", mid, name)
+		}
+
+		rs := *src
+		rs.BuildConfigs = rc.BuildConfigs
+		c, src, bc = rc, &rs, rc.BuildConfigs[name]
+	}
+
+	if bc.Lint != nil {
+		if err = src.lint(name, bc); err != nil {
+			return
+		}
+	}
+
+	if bc.ReportI18n {
+		issues, ierr := src.CheckI18n(name)
+		if ierr != nil {
+			fmt.Fprintf(c.stdout(), "Cannot check nls bundles: %s\n", ierr)
+		}
+		for _, i := range issues {
+			fmt.Fprintln(c.stdout(), i)
+		}
+	}
+
+	err = src.buildRelease(name, bc, r)
+
+	releaseDir := c.releaseDir(name)
+
+	if err != nil {
+		os.RemoveAll(releaseDir)
+		return
+	}
+
+	dc, version := c, ""
+	if bc.Layout == VersionedLayout {
+		if version, err = releaseVersion(releaseDir); err != nil {
+			os.RemoveAll(releaseDir)
+			return
+		}
+		if bc.VCSReleaseNames {
+			version = c.vcsReleaseName(version)
+		}
+
+		// The next steps work on the versioned release
+		vc, vs := *c, *src
+		vc.DestDir = filepath.Join(dc.DestDir, version)
+		vs.DestDir = vc.DestDir
+		c, src = &vc, &vs
+
+		if err = os.MkdirAll(c.DestDir, 0754); err != nil {
+			os.RemoveAll(releaseDir)
+			return
+		}
+	}
+
+	if err = removeReleaseArtifacts(releaseDir, bc); err == nil && bc.Normalize {
+		err = normalizeRelease(releaseDir)
+	}
+
+	// The versioned release dir already busts the caches
+	var cacheBust string
+	if err == nil && bc.CacheBust && version == "" {
+		cacheBust, err = releaseCacheBust(releaseDir)
+	}
+
+	if err == nil {
+		r.Excluded = &ExcludeStats{}
+		err = c.copyRelease(name, releaseDir, r.Excluded)
+	}
+
+	os.RemoveAll(releaseDir)
+
+	if err != nil {
+		return
+	}
+
+	r.Dest = c.DestDir
+
+	if cacheBust != "" {
+		if err = ioutil.WriteFile(filepath.Join(c.DestDir, cacheBustFileName(name)), []byte(cacheBust+"\n"), 0664); err != nil {
+			return
+		}
+	}
+
+	if version != "" {
+		// A canary release leaves the current one in place until Promote
+		versionFile := versionFileName(name)
+		if bc.Canary {
+			versionFile = canaryFileName(name)
+		}
+
+		if err = writeFileAtomic(filepath.Join(dc.DestDir, versionFile), []byte(version+"\n"), 0664); err != nil {
+			return
+		}
+
+		if err = dc.addReleaseVersion(name, version); err != nil {
+			return
+		}
+	}
+
+	for _, t := range bc.Themes {
+		if err = src.BuildTheme(name, t); err != nil {
+			return
+		}
+	}
+
+	if bc.InlineResourcesMaxSize > 0 {
+		if err = c.inlineLayerResources(bc, c.layerResults(bc)); err != nil {
+			return
+		}
+	}
+
+	if bc.LicenseFile != "" {
+		if err = c.writeLicenses(name, bc, c.layerResults(bc)); err != nil {
+			return
+		}
+	}
+
+	r.Layers = c.layerResults(bc)
+
+	sb, serr := c.checkLayerSizes(bc, r)
+	if err = serr; err != nil {
+		return
+	}
+
+	if bc.VerifyInBrowser {
+		if err = c.VerifyInBrowser(name); err != nil {
+			return
+		}
+	}
+
+	if err = c.RunSmokeTests(name); err != nil {
+		return
+	}
+
+	if bc.Tests != nil {
+		tr, terr := c.RunTests(name)
+		r.Tests = &tr
+		if err = terr; err != nil {
+			return
+		}
+	}
+
+	if bc.ReportExcluded {
+		r.Excluded.Print(c.stdout())
+	}
+
+	if bc.ReportInterns {
+		ir, err := c.InternReport(name)
+		if err != nil {
+			fmt.Fprintf(c.stdout(), "Cannot inspect interned templates: %s\n", err)
+		} else {
+			ir.Print(c.stdout())
+		}
+	}
+
+	if bc.SuggestLayerSplits {
+		suggestions, err := c.LayerSplitSuggestions(name)
+		if err != nil {
+			fmt.Fprintf(c.stdout(), "Cannot analyse layers: %s\n", err)
+		} else {
+			PrintLayerSplitSuggestions(c.stdout(), suggestions)
+		}
+	}
+
+	if bc.Layout == FlatLayout {
+		if err = c.flattenLayers(bc, r); err != nil {
+			return
+		}
+	}
+
+	if bc.ReportLayerReferences {
+		lr, err := c.LayerReferences(name)
+		if err != nil {
+			fmt.Fprintf(c.stdout(), "Cannot check the layer references: %s\n", err)
+		} else {
+			lr.Print(c.stdout())
+		}
+	}
+
+	if bc.ReportDeadCSS {
+		dr, err := c.DeadCSS(name)
+		if err != nil {
+			fmt.Fprintf(c.stdout(), "Cannot analyse CSS: %s\n", err)
+		} else {
+			dr.Print(c.stdout())
+		}
+	}
+
+	if bc.PreloadManifestFile != "" {
+		pm, perr := c.PreloadManifest(name)
+		if err = perr; err != nil {
+			return
+		}
+		if err = pm.Write(filepath.Join(c.DestDir, bc.PreloadManifestFile)); err != nil {
+			return
+		}
+	}
+
+	if bc.PrecacheManifestFile != "" {
+		if err = c.writePrecacheManifest(bc); err != nil {
+			return
+		}
+	}
+
+	if bc.WriteMetadata {
+		md, merr := NewMetadataWithTypes(c.DestDir, bc.ContentTypes)
+		if err = merr; err != nil {
+			return
+		}
+		if err = md.Write(c.DestDir); err != nil {
+			return
+		}
+	}
+
+	if err = c.saveSizeBaseline(sb); err != nil {
+		return
+	}
+
+	if version != "" {
+		if _, err = dc.PruneReleases(name); err != nil {
+			return
+		}
+	}
+
+	if len(bc.Deploy) > 0 {
+		err = dc.deploy(name, bc, r)
+	}
+
+	return
+}
+
+// removeReleaseArtifacts deletes from releaseDir the uncompressed and
+// consoleStripped copies of the js files the dojo builder outputs, according
+// to bc.RemoveUncompressed and bc.RemoveConsoleStripped, and the js files
+// replaced by their cross-domain version according to bc.XDomainOnly, so
+// they are not released whatever the build exclude func is.
+func removeReleaseArtifacts(releaseDir string, bc BuildConfig) error {
+	if !bc.RemoveUncompressed && !bc.RemoveConsoleStripped && !bc.XDomainOnly {
+		return nil
+	}
+
+	return filepath.Walk(releaseDir, func(path string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() {
+			return err
+		}
+
+		if (bc.RemoveUncompressed && strings.HasSuffix(path, ".uncompressed.js")) ||
+			(bc.RemoveConsoleStripped && strings.HasSuffix(path, ".consoleStripped.js")) {
+			return os.Remove(path)
+		}
+
+		if bc.XDomainOnly && isXDomainReplaced(path) {
+			return os.Remove(path)
+		}
+
+		return nil
+	})
+}
+
+// copyRelease copies the files of the release of the build config name in
+// releaseDir, not excluded by the build exclude func, into DestDir, merging
+// them with the existing ones. The excluded paths are counted into stats.
+func (c *Config) copyRelease(name, releaseDir string, stats *ExcludeStats) (err error) {
+	// Walk does not enter a root which is a symlink
+	if resolved, err := filepath.EvalSymlinks(releaseDir); err == nil {
+		releaseDir = resolved
+	}
+
+	progress := copyProgressFunc
+	p := CopyProgress{Name: name}
+
+	if progress != nil {
+		exclude, err := namedExcludeFunc(c.BuildExcludes, buildExcludeFunc, nil)
+		if err != nil {
+			return err
+		}
+
+		if p.TotalFiles, p.TotalBytes, err = copySize(releaseDir, exclude); err != nil {
+			return err
+		}
+	}
+
+	exclude, err := namedExcludeFunc(c.BuildExcludes, buildExcludeFunc, stats)
+	if err != nil {
+		return err
+	}
+
+	moves, err := c.layerDestMoves(c.BuildConfigs[name])
+	if err != nil {
+		return err
+	}
+
+	var synced []string
+	if c.SyncRelease {
+		defer func() {
+			// The directories last, so that they reference synced files
+			for i := len(synced) - 1; i >= 0 && err == nil; i-- {
+				err = syncPath(synced[i])
+			}
+		}()
+		synced = append(synced, c.DestDir)
+	}
+
+	// The directory times are set last as copying into them changes them
+	var mtime time.Time
+	var dirs []string
+	if c.BuildConfigs[name].Normalize {
+		if mtime = sourceDateEpoch(); !mtime.IsZero() {
+			defer func() {
+				for i := len(dirs) - 1; i >= 0 && err == nil; i-- {
+					err = os.Chtimes(dirs[i], mtime, mtime)
 				}
-				return
-			} else if isDir {
-				if _err = os.Mkdir(dest, 0754); _err != nil {
+			}()
+		}
+	}
+
+	err = filepath.Walk(releaseDir, func(path string, f os.FileInfo, err error) (_err error) {
+		if err != nil {
+			return err
+		} else if path == releaseDir {
+			return
+		}
+
+		isDir := f.IsDir()
+		dest, _err := destPath(releaseDir, c.DestDir, path)
+		if _err != nil {
+			return
+		}
+
+		if !isDir {
+			rel, _ := filepath.Rel(releaseDir, path)
+			if d, ok := moves[rel]; ok {
+				dest = d
+				if _err = os.MkdirAll(filepath.Dir(dest), 0754); _err != nil {
 					return
 				}
-			} else if _err = CopyFile(path, dest); _err != nil {
+			}
+		}
+
+		if skip, err := exclude(path, f); err != nil {
+			return err
+		} else if skip {
+			if isDir {
+				return filepath.SkipDir
+			}
+			return
+		} else if isDir {
+			if _err = os.Mkdir(dest, 0754); _err != nil && !os.IsExist(_err) {
+				return
+			}
+		} else {
+			if progress != nil {
+				p.File, _ = filepath.Rel(c.DestDir, dest)
+				progress(p)
+			}
+
+			if _err = copyutil.CopyFile(path, dest); _err != nil {
 				return
 			}
 
-			st := f.Sys().(*syscall.Stat_t)
+			p.Files++
+			p.Bytes += f.Size()
+		}
 
-			os.Chown(dest, int(st.Uid), int(st.Gid))
+		st := f.Sys().(*syscall.Stat_t)
 
-			return
-		})
+		os.Chown(dest, int(st.Uid), int(st.Gid))
 
-		os.RemoveAll(bc.ReleaseDir)
+		if !mtime.IsZero() {
+			if isDir {
+				dirs = append(dirs, dest)
+			} else if _err = os.Chtimes(dest, mtime, mtime); _err != nil {
+				return
+			}
+		}
+
+		if c.SyncRelease {
+			synced = append(synced, dest)
+		}
+
+		return nil
+	})
+
+	if err == nil && progress != nil {
+		p.File = ""
+		progress(p)
 	}
 
 	return
 }
 
-func (c *Config) executeBuildProfile(profilePath string) (err error) {
-	buildScriptPath := c.SrcDir + "/util/buildscripts/build.sh"
+// BuildLayer builds only the layer named layerName of the build config name
+// and copies its files into the current release of DestDir, see Layout. The
+// other files of the release, its manifests and deployments are untouched.
+func (c *Config) BuildLayer(name, layerName string) (err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return configNotFound(name)
+	}
+
+	if _, ok = bc.Layers[layerName]; !ok {
+		return layerNotFound(name, layerName)
+	}
+
+	// The layer keeps out the toolkit modules of the VendorLayer
+	if bc.VendorLayer != "" {
+		if err = c.applyVendorLayer(&bc); err != nil {
+			return
+		}
+		bc.VendorLayer = ""
+	}
+
+	bc.Layers = map[string]Layer{layerName: bc.Layers[layerName]}
+
+	lc := *c
+	lc.BuildConfigs = map[string]BuildConfig{name: bc}
+
+	if !lc.locked {
+		unlock, lerr := lc.lockDestDir()
+		if lerr != nil {
+			return lerr
+		}
+		defer unlock()
+		lc.locked = true
+	}
+
+	if lc.buildID == "" {
+		lc.buildID = newBuildID()
+	}
+
+	fmt.Fprintf(c.stdout(), "Generating layer %s of %s build (%s)\n", layerName, name, lc.buildID)
+
+	releaseDir := lc.releaseDir(name)
+	defer os.RemoveAll(releaseDir)
+
+	if err = lc.buildRelease(name, bc, &BuildResult{Name: name, BuildID: lc.buildID}); err != nil {
+		return
+	}
+
+	if err = removeReleaseArtifacts(releaseDir, bc); err != nil {
+		return
+	}
+
+	return c.mergeLayer(name, bc, layerName, releaseDir)
+}
+
+// mergeLayer copies the layer mid of bc, the build config name, built into
+// releaseDir into the current release of name in DestDir: the layer, its
+// source map, its uncompressed versions and its flattened nls bundles
+func (c *Config) mergeLayer(name string, bc BuildConfig, mid, releaseDir string) error {
+	root, err := c.ReleaseRoot(name)
+	if err != nil {
+		return err
+	}
+	destDir := filepath.Join(c.DestDir, root)
+
+	p, err := NewResolver(releaseDir, releasePackages(bc.Packages), nil).Path(mid)
+	if err != nil {
+		return err
+	}
+	if bc.XDomainOnly {
+		p = xdomainPath(p)
+	}
+
+	if _, err = os.Stat(p); err != nil {
+		return fmt.Errorf("The layer '%s' was not output: %s", mid, err)
+	}
+
+	layerFile, ok := layerDest(destDir, bc, mid)
+	if !ok && bc.Layout == FlatLayout {
+		layerFile = filepath.Join(destDir, flatLayerName(mid))
+	} else if !ok {
+		if layerFile, err = destPath(releaseDir, destDir, p); err != nil {
+			return err
+		}
+	}
+
+	// The other files keep their place in the release
+	files := map[string]string{p: layerFile, p + ".map": layerFile + ".map"}
+	base := strings.TrimSuffix(p, ".js")
+	for _, suffix := range []string{".uncompressed.js", ".consoleStripped.js"} {
+		files[base+suffix] = ""
+	}
+	nls, _ := filepath.Glob(filepath.Join(filepath.Dir(p), "nls", filepath.Base(base)+"_*.js"))
+	for _, f := range nls {
+		files[f] = ""
+	}
+
+	for src, dest := range files {
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		if dest == "" {
+			if dest, err = destPath(releaseDir, destDir, src); err != nil {
+				return err
+			}
+		}
+
+		if err = os.MkdirAll(filepath.Dir(dest), 0754); err != nil {
+			return err
+		}
+
+		// The file of the previous build may be linked elsewhere
+		os.Remove(dest)
+		if err = copyutil.CopyFile(src, dest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// command returns the command running name, wrapped with nice and ionice
+// according to Nice and IONiceClass. When the build can be canceled, the
+// command gets its own process group so that a terminal interrupt, handled
+// by the caller, doesn't kill it.
+func (c *Config) command(name string, args ...string) *exec.Cmd {
+	if c.IONiceClass > 0 {
+		args = append([]string{"-c", strconv.Itoa(c.IONiceClass), name}, args...)
+		name = "ionice"
+	}
+
+	if c.Nice > 0 {
+		args = append([]string{"-n", strconv.Itoa(c.Nice), name}, args...)
+		name = "nice"
+	}
+
+	cmd := exec.Command(name, args...)
+	if c.cancel != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+
+	return cmd
+}
+
+// stdout returns the writer of the standard output of the build processes
+func (c *Config) stdout() io.Writer {
+	if c.Output != nil {
+		return c.Output
+	}
+
+	return os.Stdout
+}
+
+// stderr returns the writer of the error output of the build processes
+func (c *Config) stderr() io.Writer {
+	if c.Output != nil {
+		return c.Output
+	}
+
+	return os.Stderr
+}
+
+// canceled reports whether the cancel channel of c is closed
+func (c *Config) canceled() bool {
+	select {
+	case <-c.cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// killOnCancel kills the started cmd and its children if the cancel channel
+// of c is closed before done is
+func (c *Config) killOnCancel(cmd *exec.Cmd, done <-chan struct{}) {
+	if c.cancel == nil {
+		return
+	}
+
+	go func() {
+		select {
+		case <-c.cancel:
+			killProcessTree(cmd.Process)
+		case <-done:
+		}
+	}()
+}
+
+func (c *Config) executeBuildProfile(bc BuildConfig, profilePath string) (err error) {
+	heap := c.MaxHeapMB
+
+	for attempt := 0; ; attempt++ {
+		oom, err := c.runBuildScript(bc, profilePath, heap)
+		if !oom || attempt >= c.OOMRetries {
+			return err
+		}
+
+		if heap == 0 {
+			heap = defaultRetryHeapMB
+		} else {
+			heap *= 2
+		}
+
+		if c.result != nil {
+			c.result.Warnings, c.result.Errors = 0, 0
+		}
+
+		fmt.Fprintf(c.stdout(), "The build ran out of memory, retrying with a %dMB heap\n", heap)
+	}
+}
+
+// runBuildScript runs the dojo build script, giving heapMB of heap to the
+// java or node optimizer when not zero. The build is killed on an out of
+// memory error, reported by oom.
+func (c *Config) runBuildScript(bc BuildConfig, profilePath string, heapMB int) (oom bool, err error) {
+	buildScriptPath := filepath.Join(c.ToolkitDir(), "util", "buildscripts", "build.sh")
 
 	args := []string{"--profile", profilePath}
 
+	if c.profile != nil {
+		// The release dir of an external profile is the one of dojoBuilder
+		releaseDir, err := filepath.Abs(bc.ReleaseDir)
+		if err != nil {
+			return false, err
+		}
+		args = append(args, "--releaseDir", releaseDir)
+	}
+
 	if c.Bin != "" {
 		args = append(args, "--bin", c.Bin)
 	}
 
-	cmd := exec.Command(buildScriptPath, args...)
+	cmd := c.command(buildScriptPath, args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return
 	}
 
-	scanner := bufio.NewScanner(stdout)
-	go func() {
-		for scanner.Scan() {
-			fmt.Println(scanner.Text())
+	if heapMB > 0 {
+		// _JAVA_OPTIONS overrides the -Xmx of build.sh
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("_JAVA_OPTIONS=-Xmx%dm", heapMB),
+			fmt.Sprintf("NODE_OPTIONS=--max-old-space-size=%d", heapMB))
+	}
+
+	var oomOnce sync.Once
+	kill := func() {
+		oomOnce.Do(func() {
+			oom = true
+			// The optimizer runs in a child of build.sh
+			killProcessTree(cmd.Process)
+		})
+	}
+
+	// The errors are kept in the output tail too
+	tail := &outputTail{max: buildOutputTailLines}
+	cmd.Stderr = io.MultiWriter(&oomWriter{w: c.stderr(), onOOM: kill}, tail)
+
+	r := NewResolver(c.SrcDir, c.sourcePackages(bc.Packages), bc.Map).WithAliases(bc.Aliases)
+
+	if err = cmd.Start(); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	peak := monitorPeakRSS(cmd.Process.Pid, done)
+	c.killOnCancel(cmd, done)
+
+	var summary []string
+	// Optimizer errors may dump a whole module on a single line
+	rerr := readLines(stdout, func(line string) {
+		fmt.Fprintln(c.stdout(), line)
+		tail.add(line)
+
+		if isOOM([]byte(line)) {
+			kill()
 		}
-	}()
 
-	err = cmd.Run()
-	if err != nil {
-		return errors.New("Build command failed")
+		if m, ok := parseBuilderMessage(line, r); ok {
+			// The first errors are the most relevant
+			if m.Level == "error" && len(summary) < buildOutputTailLines {
+				summary = append(summary, strings.TrimSpace(line))
+			}
+			c.result.addMessage(m)
+			if messageFunc != nil {
+				messageFunc(m)
+			}
+		}
+	})
+
+	err = cmd.Wait()
+
+	close(done)
+	if c.result != nil {
+		if p := <-peak; p > c.result.PeakMemory {
+			c.result.PeakMemory = p
+		}
+	}
+
+	if c.canceled() {
+		return false, ErrBuildCanceled
+	} else if oom {
+		return true, ErrOutOfMemory
+	} else if err != nil {
+		return false, newBuildFailed(cmd, err, strings.Join(summary, "\n"), tail.String())
+	} else if rerr != nil {
+		return false, fmt.Errorf("Cannot read the build output: %s", rerr)
 	}
 
 	return
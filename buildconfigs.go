@@ -0,0 +1,54 @@
+package dojoBuilder
+
+import (
+	"os"
+	"sort"
+)
+
+// BuildConfigSummary summarizes a build config for the tools presenting the
+// build choices to operators
+type BuildConfigSummary struct {
+	Name      string         `json:"name"`
+	Layers    int            `json:"layers"`
+	Packages  int            `json:"packages"`
+	Optimizer string         `json:"optimizer,omitempty"` // LayerOptimize of the build config
+	LastBuild *HistoryRecord `json:"lastBuild,omitempty"` // Last build of the History, nil if never recorded
+}
+
+// Succeeded reports whether the last recorded build succeeded
+func (s BuildConfigSummary) Succeeded() bool {
+	return s.LastBuild != nil && s.LastBuild.Error == ""
+}
+
+// ListBuildConfigs returns the summaries of the build configs, sorted by
+// name. The last builds are read from the History file when set.
+func (c *Config) ListBuildConfigs() ([]BuildConfigSummary, error) {
+	var h History
+	if c.History != "" {
+		var err error
+		if h, err = ReadHistory(c.History); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	summaries := make([]BuildConfigSummary, 0, len(c.BuildConfigs))
+
+	for name, bc := range c.BuildConfigs {
+		s := BuildConfigSummary{
+			Name:      name,
+			Layers:    len(bc.Layers),
+			Packages:  len(bc.Packages),
+			Optimizer: bc.LayerOptimize,
+		}
+
+		if records := h.ForBuild(name); len(records) > 0 {
+			s.LastBuild = &records[len(records)-1]
+		}
+
+		summaries = append(summaries, s)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	return summaries, nil
+}
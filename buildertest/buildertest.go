@@ -0,0 +1,100 @@
+// Package buildertest provides a fake dojo builder so applications embedding
+// dojoBuilder can test their build orchestration without Java, Node or a
+// dojo checkout.
+package buildertest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+const fakeLayerContent = "define([], function(){});\n"
+
+// FakeBuilder simulates the dojo builder by writing a fake release tree.
+//
+// If Files is nil, a fake module is written for every layer of the build
+// config, at the path the dojo builder would output it.
+type FakeBuilder struct {
+	Files map[string]string // Release files (path relative to the release dir => content)
+	Err   error             // Error returned by every build (optional)
+
+	mu       sync.Mutex
+	profiles []string
+}
+
+// Install sets b as the dojoBuilder build func
+func (b *FakeBuilder) Install() { dojoBuilder.SetBuildFunc(b.Build) }
+
+// Uninstall restores the default dojoBuilder build func
+func (b *FakeBuilder) Uninstall() { dojoBuilder.SetBuildFunc(dojoBuilder.DefaultBuildFunc) }
+
+// Profiles returns the paths of the profiles built so far
+func (b *FakeBuilder) Profiles() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string(nil), b.profiles...)
+}
+
+// Build implements dojoBuilder.BuildFunc
+func (b *FakeBuilder) Build(c *dojoBuilder.Config, bc dojoBuilder.BuildConfig, profilePath string) (err error) {
+	b.mu.Lock()
+	b.profiles = append(b.profiles, profilePath)
+	b.mu.Unlock()
+
+	if _, err = os.Stat(profilePath); err != nil {
+		return
+	}
+
+	if b.Err != nil {
+		return b.Err
+	}
+
+	files := b.Files
+	if files == nil {
+		if files, err = layerFiles(bc); err != nil {
+			return
+		}
+	}
+
+	for p, content := range files {
+		dest := filepath.Join(bc.ReleaseDir, p)
+
+		if err = os.MkdirAll(filepath.Dir(dest), 0754); err != nil {
+			return
+		}
+
+		if err = ioutil.WriteFile(dest, []byte(content), 0664); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// layerFiles returns a fake release file for each layer of bc
+func layerFiles(bc dojoBuilder.BuildConfig) (map[string]string, error) {
+	packages := make([]dojoBuilder.Package, len(bc.Packages))
+	for i, p := range bc.Packages {
+		p.Location = p.ReleaseLocation()
+		packages[i] = p
+	}
+
+	r := dojoBuilder.NewResolver("", packages, nil)
+	files := make(map[string]string, len(bc.Layers))
+
+	for mid := range bc.Layers {
+		p, err := r.Path(mid)
+		if err != nil {
+			return nil, err
+		}
+
+		files[p] = fakeLayerContent
+	}
+
+	return files, nil
+}
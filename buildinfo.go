@@ -0,0 +1,59 @@
+package dojoBuilder
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+)
+
+// BuildInfoFileName is the name of the file written in DestDir describing
+// the last successful Build run
+const BuildInfoFileName = "dojoBuilder.build.json"
+
+// BuildIDEnv is the environment variable giving the build id to the smoke
+// tests and test suites
+const BuildIDEnv = "DOJOBUILDER_BUILD_ID"
+
+// BuildInfo describes a Build run. Its BuildID is found in the logs, the
+// history, the manifests and the environment of the tests of the run.
+type BuildInfo struct {
+	BuildID string    `json:"buildId"`
+	Time    time.Time `json:"time"`
+	Commit  string    `json:"commit,omitempty"`  // Commit built, if known, see Config.VCS
+	Version string    `json:"version,omitempty"` // VCS version built, if known
+	Branch  string    `json:"branch,omitempty"`  // VCS branch built, if known
+	Builds  []string  `json:"builds"`            // Names of the build configs built
+}
+
+// newBuildID returns a unique id for a run of Build
+func newBuildID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+
+	return time.Now().Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
+
+// ReadBuildInfo reads the BuildInfoFileName file of dir
+func ReadBuildInfo(dir string) (i *BuildInfo, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, BuildInfoFileName))
+	if err != nil {
+		return
+	}
+
+	i = &BuildInfo{}
+	err = json.Unmarshal(b, i)
+
+	return
+}
+
+func (i *BuildInfo) write(dir string) error {
+	b, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, BuildInfoFileName), append(b, '\n'), 0664)
+}
@@ -0,0 +1,147 @@
+package dojoBuilder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
+)
+
+// buildIDPlaceholder replaces the build id in the cache keys and in the
+// paths of the cached releases
+const buildIDPlaceholder = "BUILDID"
+
+// buildCacheKey returns the key of the release of the profile at profilePath
+// of bc in the CacheDir: the hash of the profile, without the paths specific
+// to the build, and of the sources of its packages
+func (c *Config) buildCacheKey(bc BuildConfig, profilePath string) (string, error) {
+	profile, err := ioutil.ReadFile(profilePath)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(c.cacheNeutral(string(profile))))
+
+	packages := append([]Package(nil), bc.Packages...)
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+
+	for _, p := range packages {
+		dir, err := filepath.EvalSymlinks(p.Dir(c.SrcDir))
+		if err != nil {
+			return "", err
+		}
+
+		sums, err := treeChecksums(dir)
+		if err != nil {
+			return "", err
+		}
+
+		paths := make([]string, 0, len(sums))
+		for rel := range sums {
+			paths = append(paths, rel)
+		}
+		sort.Strings(paths)
+
+		fmt.Fprintf(h, "%s\x00", p.Name)
+		for _, rel := range paths {
+			fmt.Fprintf(h, "%s\x00%x\x00", filepath.ToSlash(rel), sums[rel])
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheNeutral returns s without the build id and the directories of c,
+// which differ from a build, or an application, to the other
+func (c *Config) cacheNeutral(s string) string {
+	var pairs []string
+	for _, d := range []string{c.DestDir, c.SrcDir} {
+		if d != "" {
+			pairs = append(pairs, filepath.ToSlash(d), "", d, "")
+		}
+	}
+	if c.buildID != "" {
+		pairs = append(pairs, c.buildID, buildIDPlaceholder)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// copyCachedRelease copies the release src into dest, renaming the paths
+// containing the build id from into the build id to. The files are copied,
+// not linked, the release being changed after the build.
+func copyCachedRelease(src, dest, from, to string) error {
+	return filepath.Walk(src, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if from != "" {
+			rel = strings.Replace(rel, from, to, -1)
+		}
+
+		target, err := destPath(dest, dest, filepath.Join(dest, rel))
+		if err != nil {
+			return err
+		}
+
+		if f.IsDir() {
+			return os.MkdirAll(target, 0754)
+		}
+
+		return copyutil.CopyContents(path, target)
+	})
+}
+
+// cachedBuild runs the build of the profile at profilePath of bc, reusing
+// the release of the same profile and sources found in the CacheDir
+func (c *Config) cachedBuild(bc BuildConfig, profilePath string) (err error) {
+	if c.CacheDir == "" {
+		return buildFunc(c, bc, profilePath)
+	}
+
+	key, err := c.buildCacheKey(bc, profilePath)
+	if err != nil {
+		fmt.Fprintf(c.stdout(), "Warning: cannot compute the build cache key: %s\n", err)
+		return buildFunc(c, bc, profilePath)
+	}
+
+	cached := filepath.Join(c.CacheDir, key)
+
+	if _, serr := os.Stat(cached); serr == nil {
+		if err = copyCachedRelease(cached, bc.ReleaseDir, buildIDPlaceholder, c.buildID); err == nil {
+			fmt.Fprintf(c.stdout(), "Reusing the cached build %s\n", key[:10])
+			return
+		}
+		fmt.Fprintf(c.stdout(), "Warning: cannot reuse the cached build %s: %s\n", key[:10], err)
+		os.RemoveAll(bc.ReleaseDir)
+	}
+
+	if err = buildFunc(c, bc, profilePath); err != nil {
+		return
+	}
+
+	// Concurrent builds of the same release keep the first one cached
+	tmp := c.workPath(c.CacheDir, key, "")
+	if err = copyCachedRelease(bc.ReleaseDir, tmp, c.buildID, buildIDPlaceholder); err == nil {
+		if rerr := os.Rename(tmp, cached); rerr != nil {
+			os.RemoveAll(tmp)
+		}
+	} else {
+		fmt.Fprintf(c.stdout(), "Warning: cannot cache the build: %s\n", err)
+		os.RemoveAll(tmp)
+	}
+
+	return nil
+}
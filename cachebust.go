@@ -0,0 +1,56 @@
+package dojoBuilder
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// cacheBustFileName returns the name of the file of DestDir storing the
+// cache-busting query string of the last release of the build config name
+func cacheBustFileName(name string) string {
+	return "dojoBuilder." + name + ".cachebust"
+}
+
+// releaseCacheBust returns the cache-busting query string of the release in
+// releaseDir, "v=" followed by the hash of its content
+func releaseCacheBust(releaseDir string) (string, error) {
+	v, err := releaseVersion(releaseDir)
+	if err != nil {
+		return "", err
+	}
+
+	return "v=" + strings.TrimPrefix(v, "v"), nil
+}
+
+// CacheBust returns the cache-busting query string, without "?", of the
+// last release of the build config name: "" unless its CacheBust is set.
+// It is meant for the cacheBust of the dojoConfig, so that the modules the
+// loader requests have the query string of the urls of LayoutFuncs and of
+// the preload manifest.
+func (c *Config) CacheBust(name string) (string, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return "", configNotFound(name)
+	}
+
+	if !bc.CacheBust || bc.Layout == VersionedLayout {
+		return "", nil
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(c.DestDir, cacheBustFileName(name)))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// withCacheBust appends the query string q, if any, to the url u
+func withCacheBust(u, q string) string {
+	if q == "" {
+		return u
+	}
+
+	return u + "?" + q
+}
@@ -0,0 +1,141 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// canaryFileName returns the name of the file of DestDir storing the
+// directory of the canary release of the build config name
+func canaryFileName(name string) string {
+	return "dojoBuilder." + name + ".canary"
+}
+
+// validateCanary checks that the Canary of bc applies to its Layout
+func validateCanary(bc BuildConfig) error {
+	if bc.Canary && bc.Layout != VersionedLayout {
+		return errors.New("Canary requires the VersionedLayout")
+	}
+
+	return nil
+}
+
+// versionedConfig returns the VersionedLayout build config name
+func (c *Config) versionedConfig(name string) (bc BuildConfig, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return bc, configNotFound(name)
+	}
+
+	if bc.Layout != VersionedLayout {
+		return bc, errors.New("The build config '" + name + "' does not use the VersionedLayout")
+	}
+
+	return
+}
+
+// CanaryRoot returns the directory, relative to DestDir, of the canary
+// release of the build config name: "" when it has none.
+func (c *Config) CanaryRoot(name string) (string, error) {
+	if _, err := c.versionedConfig(name); err != nil {
+		return "", err
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(c.DestDir, canaryFileName(name)))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// CanaryLayoutFuncs returns the LayoutFuncs of the canary release of the
+// build config name, for the pages served to the canary traffic. They fail
+// when the build config has no canary release.
+func (c *Config) CanaryLayoutFuncs(name string) template.FuncMap {
+	return c.layoutFuncs(name, func() (string, error) {
+		root, err := c.CanaryRoot(name)
+		if err == nil && root == "" {
+			err = errors.New("No canary release of build config '" + name + "'")
+		}
+		return root, err
+	})
+}
+
+// Promote makes the canary release of the build config name its current
+// release, then prunes the releases exceeding its Retention. DestDir is
+// locked meanwhile, see LockTimeout.
+func (c *Config) Promote(name string) error {
+	if !c.locked {
+		unlock, err := c.lockDestDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		lc := *c
+		lc.locked = true
+		c = &lc
+	}
+
+	root, err := c.CanaryRoot(name)
+	if err != nil {
+		return err
+	} else if root == "" {
+		return errors.New("No canary release of build config '" + name + "'")
+	}
+
+	if err = writeFileAtomic(filepath.Join(c.DestDir, versionFileName(name)), []byte(root+"\n"), 0664); err != nil {
+		return err
+	}
+
+	if err = os.Remove(filepath.Join(c.DestDir, canaryFileName(name))); err != nil {
+		return err
+	}
+
+	// The promoted release is the latest one for the Retention
+	if err = c.addReleaseVersion(name, root); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout(), "Promoted release %s of %s\n", root, name)
+
+	if err = c.audit(AuditEvent{Action: AuditPromote, Name: name, Target: root}); err != nil {
+		return err
+	}
+
+	_, err = c.PruneReleases(name)
+
+	return err
+}
+
+// AbortCanary drops the canary release of the build config name, the current
+// release staying untouched. The canary release dir is left to the Retention.
+// DestDir is locked meanwhile, see LockTimeout.
+func (c *Config) AbortCanary(name string) error {
+	if _, err := c.versionedConfig(name); err != nil {
+		return err
+	}
+
+	if !c.locked {
+		unlock, err := c.lockDestDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	err := os.Remove(filepath.Join(c.DestDir, canaryFileName(name)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
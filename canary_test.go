@@ -0,0 +1,52 @@
+package dojoBuilder_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tbaud0n/dojoBuilder"
+	"github.com/tbaud0n/dojoBuilder/buildertest"
+)
+
+func TestPromote(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestConfig(t, dir, "a")
+
+	bc := c.BuildConfigs["a"]
+	bc.Layout = dojoBuilder.VersionedLayout
+	bc.Canary = true
+	c.BuildConfigs["a"] = bc
+
+	fb := &buildertest.FakeBuilder{}
+	fb.Install()
+	defer fb.Uninstall()
+
+	if _, err := c.Build(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	canary, err := c.CanaryRoot("a")
+	if err != nil || canary == "" {
+		t.Fatalf("CanaryRoot returned %q, %v", canary, err)
+	}
+
+	if err = c.Promote("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if root, err := c.ReleaseRoot("a"); err != nil || root != canary {
+		t.Errorf("ReleaseRoot returned %q, %v, want the promoted release %s", root, err, canary)
+	}
+	if root, err := c.CanaryRoot("a"); err != nil || root != "" {
+		t.Errorf("CanaryRoot returned %q, %v after Promote", root, err)
+	}
+
+	// The version file is written through a temporary file
+	tmps, err := filepath.Glob(filepath.Join(c.DestDir, ".dojoBuilder.*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmps) > 0 {
+		t.Errorf("%v left in DestDir", tmps)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func runExplain(args []string) (err error) {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	build := fs.String("build", "", "Build config to explain")
+	fs.Parse(args)
+
+	if *build == "" {
+		return errors.New("The build config is required (-build)")
+	}
+
+	c, err := dojoBuilder.LoadConfigFile(*configPath)
+	if err != nil {
+		return
+	}
+
+	profile, err := c.Explain(*build)
+	if err != nil {
+		return
+	}
+
+	fmt.Println(profile)
+
+	return
+}
+
+func runDiffConfig(args []string) (err error) {
+	fs := flag.NewFlagSet("diff-config", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	otherPath := fs.String("other", "", "Path of the config file to compare with (default -config)")
+	build := fs.String("build", "", "Build config to compare")
+	otherBuild := fs.String("other-build", "", "Build config of the other config file to compare with (default -build)")
+	fs.Parse(args)
+
+	if *build == "" {
+		return errors.New("The build config is required (-build)")
+	}
+	if *otherPath == "" {
+		*otherPath = *configPath
+	}
+	if *otherBuild == "" {
+		*otherBuild = *build
+	}
+	if *otherPath == *configPath && *otherBuild == *build {
+		return errors.New("Nothing to compare, set -other or -other-build")
+	}
+
+	var profiles [2]string
+	for i, s := range [][2]string{{*configPath, *build}, {*otherPath, *otherBuild}} {
+		c, err := dojoBuilder.LoadConfigFile(s[0])
+		if err != nil {
+			return err
+		}
+
+		if profiles[i], err = c.Explain(s[1]); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("--- %s (%s)\n+++ %s (%s)\n", *configPath, *build, *otherPath, *otherBuild)
+	for _, l := range lineDiff(strings.Split(profiles[0], "\n"), strings.Split(profiles[1], "\n")) {
+		fmt.Println(l)
+	}
+
+	return
+}
+
+// diffContext is the number of unchanged lines shown around the changes
+const diffContext = 3
+
+// lineDiff returns the lines of a and b prefixed by "-" when only in a, "+"
+// when only in b and " " when in both. Unchanged lines further than
+// diffContext from a change are elided.
+func lineDiff(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+
+	// Keep the unchanged lines close to a change
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l[0] == ' ' {
+			continue
+		}
+		for n := k - diffContext; n <= k+diffContext; n++ {
+			if n >= 0 && n < len(lines) {
+				keep[n] = true
+			}
+		}
+	}
+
+	var shown []string
+	for k, l := range lines {
+		if keep[k] {
+			shown = append(shown, l)
+		} else if k == 0 || keep[k-1] {
+			shown = append(shown, "...")
+		}
+	}
+
+	return shown
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+const exampleTemplate = `//go:build ignore
+// +build ignore
+
+// Example integration of dojoBuilder generated by "dojobuilder init"
+package main
+
+import (
+	"log"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func main() {
+	c, err := dojoBuilder.LoadConfigFile({{printf "%q" .ConfigPath}})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dojoBuilder.SetInstallExcludeFunc(dojoBuilder.DefaultInstallExcludeFunc)
+	dojoBuilder.SetBuildExcludeFunc(dojoBuilder.DefaultBuildExcludeFunc)
+
+	if err := dojoBuilder.Run(c, nil, true); err != nil {
+		log.Fatal(err)
+	}
+}
+`
+
+// Directories of SrcDir which are never packages
+var skippedDirs = map[string]bool{"util": true, "profiles": true, "node_modules": true}
+
+// Names of the modules looked for as entry points
+var entryModuleNames = []string{"main", "app", "index"}
+
+func runInit(args []string) (err error) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	srcDir := fs.String("src", ".", "Directory containing the dojo checkout and the application packages")
+	destDir := fs.String("dest", "", "Directory where the built files will be placed (default <src>/../release)")
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file to write")
+	goPath := fs.String("go", "dojobuilder_example.go", "Path of the example Go integration code to write (empty to skip)")
+	preset := fs.String("preset", "app", "Build config preset: app, or micro for the smallest dojo.js of a widget embedded in third-party pages")
+	fs.Parse(args)
+
+	if *preset != "app" && *preset != "micro" {
+		return fmt.Errorf("Unknown preset '%s'", *preset)
+	}
+
+	src, err := filepath.Abs(*srcDir)
+	if err != nil {
+		return
+	}
+
+	dest := *destDir
+	if dest == "" {
+		dest = filepath.Join(filepath.Dir(src), "release")
+	}
+	if dest, err = filepath.Abs(dest); err != nil {
+		return
+	}
+
+	packages, entries, err := scanSrcDir(src)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Found packages:")
+	for _, p := range packages {
+		fmt.Printf(" %s", p.Name)
+	}
+	fmt.Println()
+
+	defaultEntry := "app/main"
+	if len(entries) > 0 {
+		defaultEntry = entries[0]
+	}
+
+	in := bufio.NewReader(os.Stdin)
+
+	entry := ask(in, "Entry module", defaultEntry)
+	optimizer := ask(in, "Optimizer (closure, uglify, shrinksafe, none)", "closure")
+	locales := ask(in, "Locales to flatten into layers (comma separated)", "")
+	sourceMaps := ask(in, "Generate source maps (y/n)", "n")
+
+	appPackage := strings.SplitN(entry, "/", 2)[0]
+
+	var bc dojoBuilder.BuildConfig
+	if *preset == "micro" {
+		// Only dojo and the widget package are built
+		bc = dojoBuilder.NewMicroBuildConfig(appPackage, entry)
+		bc.Packages = nil
+		for _, p := range packages {
+			if p.Name == "dojo" || p.Name == appPackage {
+				bc.Packages = append(bc.Packages, p)
+			}
+		}
+	} else {
+		bc = dojoBuilder.NewSinglePageAppConfig(appPackage, entry)
+		bc.Packages = packages
+	}
+	bc.UseSourceMaps = strings.HasPrefix(strings.ToLower(sourceMaps), "y")
+
+	if optimizer == "none" {
+		bc.LayerOptimize = dojoBuilder.NoLayerOptimize
+	} else {
+		bc.LayerOptimize = optimizer
+	}
+
+	for _, l := range strings.Split(locales, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			bc.LocaleList = append(bc.LocaleList, l)
+		}
+	}
+
+	c := &dojoBuilder.Config{
+		BuildMode:    true,
+		SrcDir:       src,
+		DestDir:      dest,
+		BuildConfigs: map[string]dojoBuilder.BuildConfig{"default": bc},
+	}
+
+	if err = writeConfigFile(c, *configPath); err != nil {
+		return
+	}
+	fmt.Printf("Config written to %s\n", *configPath)
+
+	if *goPath == "" {
+		return
+	}
+
+	if err = writeExample(*goPath, *configPath); err != nil {
+		return
+	}
+	fmt.Printf("Example Go integration written to %s\n", *goPath)
+
+	return
+}
+
+// scanSrcDir returns a package for each directory of srcDir containing js
+// files, and the entry modules found in the non toolkit packages.
+func scanSrcDir(srcDir string) (packages []dojoBuilder.Package, entries []string, err error) {
+	infos, err := ioutil.ReadDir(srcDir)
+	if err != nil {
+		return
+	}
+
+	toolkit := make(map[string]bool)
+	for _, p := range dojoBuilder.ToolkitPackages() {
+		toolkit[p.Name] = true
+	}
+
+	for _, fi := range infos {
+		name := fi.Name()
+		if !fi.IsDir() || skippedDirs[name] || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if !containsJS(filepath.Join(srcDir, name)) {
+			continue
+		}
+
+		packages = append(packages, dojoBuilder.Package{Name: name, Location: name})
+
+		if toolkit[name] {
+			continue
+		}
+
+		for _, m := range entryModuleNames {
+			if _, err := os.Stat(filepath.Join(srcDir, name, m+".js")); err == nil {
+				entries = append(entries, name+"/"+m)
+				break
+			}
+		}
+	}
+
+	sort.Strings(entries)
+
+	return
+}
+
+// containsJS returns true if dir or one of its sub directories contains a js file
+func containsJS(dir string) (found bool) {
+	filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if err != nil || found {
+			return filepath.SkipDir
+		}
+		if !f.IsDir() && filepath.Ext(path) == ".js" {
+			found = true
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	return
+}
+
+// ask prints question and returns the answer read from in, or def if empty
+func ask(in *bufio.Reader, question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	answer, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return def
+	}
+
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+
+	return answer
+}
+
+// relativeTo returns path relative to dir if possible
+func relativeTo(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return rel
+	}
+
+	return path
+}
+
+func writeExample(path, configPath string) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	t := template.Must(template.New("example").Parse(exampleTemplate))
+
+	return t.Execute(f, map[string]string{"ConfigPath": configPath})
+}
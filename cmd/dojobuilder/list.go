@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func runList(args []string) (err error) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	asJSON := fs.Bool("json", false, "Print the summaries as JSON")
+	fs.Parse(args)
+
+	c, err := dojoBuilder.LoadConfigFile(*configPath)
+	if err != nil {
+		return
+	}
+
+	summaries, err := c.ListBuildConfigs()
+	if err != nil {
+		return
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLAYERS\tPACKAGES\tOPTIMIZER\tLAST BUILD")
+
+	for _, s := range summaries {
+		last := "never"
+		if s.LastBuild != nil {
+			status := "ok"
+			if !s.Succeeded() {
+				status = "failed"
+			}
+			last = fmt.Sprintf("%s (%s)", status, s.LastBuild.Time.Format(time.RFC3339))
+		}
+
+		optimizer := s.Optimizer
+		if optimizer == "" {
+			optimizer = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", s.Name, s.Layers, s.Packages, optimizer, last)
+	}
+
+	return w.Flush()
+}
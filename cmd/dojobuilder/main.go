@@ -0,0 +1,88 @@
+// Command dojobuilder helps setting up and running dojoBuilder builds.
+//
+// Usage:
+//
+//	dojobuilder init [flags]          Generate a config file by answering a few questions
+//	dojobuilder scaffold [flags]      Create a new package skeleton and register it in the config
+//	dojobuilder watch [flags]         Rebuild whenever the sources or the config file change
+//	dojobuilder serve [flags]         Serve the sources, building the layers on first request
+//	dojobuilder upgrade [flags]       Migrate the config file to the current schema version
+//	dojobuilder explain [flags]       Print the profile of a build config as the dojo builder receives it
+//	dojobuilder diff-config [flags]   Compare the profiles of two build configs
+//	dojobuilder list [flags]          List the build configs with their last build status
+//	dojobuilder promote [flags]       Promote the canary release of a build config
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"init", "Generate a config file by answering a few questions", runInit},
+	{"scaffold", "Create a new package skeleton and register it in the config", runScaffold},
+	{"watch", "Rebuild whenever the sources or the config file change", runWatch},
+	{"serve", "Serve the sources, building the layers on first request", runServe},
+	{"upgrade", "Migrate the config file to the current schema version", runUpgrade},
+	{"explain", "Print the profile of a build config as the dojo builder receives it", runExplain},
+	{"diff-config", "Compare the profiles of two build configs", runDiffConfig},
+	{"list", "List the build configs with their last build status", runList},
+	{"promote", "Promote the canary release of a build config", runPromote},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: dojobuilder <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				if e, ok := err.(exitError); ok {
+					os.Exit(e.code)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	usage()
+	os.Exit(2)
+}
+
+// writeConfigFile writes c into the config file at path, with SrcDir, DojoDir
+// and DestDir relative to the directory of the file
+func writeConfigFile(c *dojoBuilder.Config, path string) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	fc := *c
+	fc.SrcDir = relativeTo(dir, c.SrcDir)
+	fc.DestDir = relativeTo(dir, c.DestDir)
+	if c.DojoDir != "" {
+		fc.DojoDir = relativeTo(dir, c.DojoDir)
+	}
+
+	return fc.WriteFile(path)
+}
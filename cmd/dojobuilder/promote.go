@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func runPromote(args []string) (err error) {
+	fs := flag.NewFlagSet("promote", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	build := fs.String("build", "", "Build config whose canary release is promoted")
+	abort := fs.Bool("abort", false, "Drop the canary release instead of promoting it")
+	fs.Parse(args)
+
+	if *build == "" {
+		return errors.New("The build config is required (-build)")
+	}
+
+	c, err := dojoBuilder.LoadConfigFile(*configPath)
+	if err != nil {
+		return
+	}
+
+	if *abort {
+		return c.AbortCanary(*build)
+	}
+
+	return c.Promote(*build)
+}
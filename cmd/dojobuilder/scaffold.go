@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func runScaffold(args []string) (err error) {
+	fs := flag.NewFlagSet("scaffold", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	name := fs.String("name", "", "Name of the package to create")
+	builds := fs.String("build", "", "Comma separated build configs to add the package to (default all)")
+	fs.Parse(args)
+
+	if *name == "" {
+		return errors.New("The package name is required (-name)")
+	}
+
+	c, err := dojoBuilder.LoadConfigFile(*configPath)
+	if err != nil {
+		return
+	}
+
+	var names []string
+	if *builds != "" {
+		names = strings.Split(*builds, ",")
+	} else {
+		for n := range c.BuildConfigs {
+			names = append(names, n)
+		}
+	}
+
+	if err = c.Scaffold(*name, names); err != nil {
+		return
+	}
+
+	if err = writeConfigFile(c, *configPath); err != nil {
+		return
+	}
+
+	fmt.Printf("Package %s created in %s and added to %s\n", *name, c.SrcDir, strings.Join(names, ", "))
+
+	return
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func runServe(args []string) (err error) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	build := fs.String("build", "", "Build config whose layers are built on demand")
+	addr := fs.String("addr", ":8080", "Address to serve on")
+	fs.Parse(args)
+
+	if *build == "" {
+		return errors.New("The build config is required (-build)")
+	}
+
+	c, err := dojoBuilder.LoadConfigFile(*configPath)
+	if err != nil {
+		return
+	}
+
+	s, err := c.NewLazyLayerServer(*build)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("Serving %s on %s, layers of %s are built on first request\n", c.SrcDir, *addr, *build)
+
+	srv := &http.Server{Addr: *addr, Handler: s}
+
+	sh := handleSignals("request")
+	defer sh.release()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-sh.stop
+
+		// Let the layers being built finish, unless signaled again
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-sh.cancel:
+				cancel()
+			case <-done:
+			}
+		}()
+		srv.Shutdown(ctx)
+	}()
+
+	if err = srv.ListenAndServe(); err != http.ErrServerClosed {
+		return
+	}
+
+	<-done
+
+	return sh.canceled()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// exitError makes main exit with code instead of 1
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string { return e.err.Error() }
+
+// shutdown tracks the SIGINT and SIGTERM received by a long-running command:
+// stop is closed on the first one and cancel on the second one.
+type shutdown struct {
+	stop   chan struct{}
+	cancel chan struct{}
+	sigs   chan os.Signal
+
+	mu  sync.Mutex
+	sig os.Signal
+}
+
+func handleSignals(what string) *shutdown {
+	s := &shutdown{
+		stop:   make(chan struct{}),
+		cancel: make(chan struct{}),
+		sigs:   make(chan os.Signal, 2),
+	}
+
+	signal.Notify(s.sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		for _, c := range []chan struct{}{s.stop, s.cancel} {
+			sig, ok := <-s.sigs
+			if !ok {
+				return
+			}
+
+			s.mu.Lock()
+			s.sig = sig
+			s.mu.Unlock()
+
+			if c == s.stop {
+				fmt.Fprintf(os.Stderr, "Received %s, stopping after the %s in progress (again to cancel it)\n", sig, what)
+			} else {
+				fmt.Fprintf(os.Stderr, "Received %s, canceling the %s in progress\n", sig, what)
+			}
+			close(c)
+		}
+	}()
+
+	return s
+}
+
+// release stops the signal handling
+func (s *shutdown) release() {
+	signal.Stop(s.sigs)
+	close(s.sigs)
+}
+
+// canceled returns an exitError with the conventional 128+signal status if
+// the work in progress was canceled, nil otherwise
+func (s *shutdown) canceled() error {
+	select {
+	case <-s.cancel:
+	default:
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := 130
+	if sig, ok := s.sig.(syscall.Signal); ok {
+		code = 128 + int(sig)
+	}
+
+	return exitError{code, fmt.Errorf("Canceled by %s", s.sig)}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func runUpgrade(args []string) (err error) {
+	fs := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	fs.Parse(args)
+
+	c, err := dojoBuilder.LoadConfigFile(*configPath)
+	if err != nil {
+		return
+	}
+
+	if err = writeConfigFile(c, *configPath); err != nil {
+		return
+	}
+
+	fmt.Printf("%s upgraded to schemaVersion %d\n", *configPath, dojoBuilder.ConfigSchemaVersion)
+
+	return
+}
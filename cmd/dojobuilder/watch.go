@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func runWatch(args []string) (err error) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	configPath := fs.String("config", "dojobuilder.json", "Path of the config file")
+	builds := fs.String("build", "", "Comma separated build configs to build (default all)")
+	notify := fs.Bool("notify", false, "Show a desktop notification after every build")
+	liveReload := fs.Bool("livereload", false, "Serve LiveReload on "+dojoBuilder.DefaultLiveReloadAddr+" and reload the browser after every build")
+	browserSync := fs.String("browsersync", "", "Url of a browser-sync server to reload after every build")
+	serve := fs.String("serve", "", "Address to serve DestDir on, with the build error overlay at "+dojoBuilder.DevOverlayPath)
+	interval := fs.Duration("interval", dojoBuilder.DefaultWatchInterval, "Interval between two checks for changes")
+	fs.Parse(args)
+
+	c, err := dojoBuilder.LoadConfigFile(*configPath)
+	if err != nil {
+		return
+	}
+
+	w := &dojoBuilder.Watcher{Config: c, ConfigFile: *configPath, Interval: *interval}
+	if *notify {
+		w.Notifier = dojoBuilder.DesktopNotifier{}
+	}
+	if *liveReload {
+		lr := &dojoBuilder.LiveReloadServer{}
+		go func() {
+			if err := http.ListenAndServe(dojoBuilder.DefaultLiveReloadAddr, lr); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+		w.Reloader = lr
+	} else if *browserSync != "" {
+		w.Reloader = dojoBuilder.BrowserSync{URL: *browserSync}
+	}
+	if *builds != "" {
+		w.Names = strings.Split(*builds, ",")
+	}
+
+	if *serve != "" {
+		go func() {
+			if err := http.ListenAndServe(*serve, w.DevHandler(true)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+
+	s := handleSignals("build")
+	defer s.release()
+	w.Cancel = s.cancel
+
+	if err = w.Run(s.stop); err != nil {
+		return
+	}
+
+	if err = s.canceled(); err != nil {
+		return
+	}
+
+	if lerr := w.LastError(); lerr != nil {
+		return fmt.Errorf("Last build failed: %s", lerr)
+	}
+
+	return
+}
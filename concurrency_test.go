@@ -0,0 +1,209 @@
+package dojoBuilder_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tbaud0n/dojoBuilder"
+	"github.com/tbaud0n/dojoBuilder/buildertest"
+)
+
+// checkNoWorkFiles fails if dir contains intermediate files of the builds
+func checkNoWorkFiles(t *testing.T, dir string) {
+	t.Helper()
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	for _, fi := range infos {
+		if strings.HasPrefix(fi.Name(), "dojoBuilder") && fi.Name() != dojoBuilder.BuildInfoFileName {
+			t.Errorf("%s left in %s", fi.Name(), dir)
+		}
+	}
+}
+
+// Copies of a config sharing SrcDir build concurrently into their DestDir
+func TestConcurrentBuilds(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestConfig(t, dir, "a", "b")
+
+	fb := &buildertest.FakeBuilder{}
+	fb.Install()
+	defer fb.Uninstall()
+
+	const builds = 4
+
+	var wg sync.WaitGroup
+	errs := make([]error, builds)
+	dests := make([]string, builds)
+
+	for i := 0; i < builds; i++ {
+		cc := *c
+		cc.DestDir = filepath.Join(dir, fmt.Sprintf("dest%d", i))
+		dests[i] = cc.DestDir
+
+		wg.Add(1)
+		go func(i int, cc *dojoBuilder.Config) {
+			defer wg.Done()
+			_, errs[i] = cc.Build(nil)
+		}(i, &cc)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Build %d: %s", i, err)
+		}
+	}
+
+	for _, dest := range dests {
+		if _, err := os.Stat(filepath.Join(dest, "app", "main.js")); err != nil {
+			t.Error(err)
+		}
+		checkNoWorkFiles(t, dest)
+	}
+
+	if n := len(fb.Profiles()); n != 2*builds {
+		t.Errorf("%d profiles built, want %d", n, 2*builds)
+	}
+	checkNoWorkFiles(t, filepath.Join(c.SrcDir, "profiles"))
+}
+
+// Two configs sharing DestDir build one after the other, see LockTimeout
+func TestSharedDestDir(t *testing.T) {
+	dir := t.TempDir()
+	c1 := newTestConfig(t, dir, "a")
+	c2 := newTestConfig(t, dir, "b")
+
+	fb := &buildertest.FakeBuilder{}
+
+	var active, overlaps int32
+	dojoBuilder.SetBuildFunc(func(c *dojoBuilder.Config, bc dojoBuilder.BuildConfig, profilePath string) error {
+		if atomic.AddInt32(&active, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		defer atomic.AddInt32(&active, -1)
+
+		time.Sleep(50 * time.Millisecond)
+
+		return fb.Build(c, bc, profilePath)
+	})
+	defer fb.Uninstall()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+
+	for i, c := range []*dojoBuilder.Config{c1, c2} {
+		wg.Add(1)
+		go func(i int, c *dojoBuilder.Config) {
+			defer wg.Done()
+			_, errs[i] = c.Build(nil)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Build %d: %s", i, err)
+		}
+	}
+
+	if overlaps > 0 {
+		t.Errorf("The builds of the shared DestDir overlapped")
+	}
+	if n := len(fb.Profiles()); n != 2 {
+		t.Errorf("%d profiles built, want 2", n)
+	}
+	checkNoWorkFiles(t, c1.DestDir)
+}
+
+// A build of a DestDir locked by another one fails after LockTimeout
+func TestSharedDestDirLockTimeout(t *testing.T) {
+	dir := t.TempDir()
+	c1 := newTestConfig(t, dir, "a")
+	c2 := newTestConfig(t, dir, "b")
+	c2.LockTimeout = -1
+
+	fb := &buildertest.FakeBuilder{}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	dojoBuilder.SetBuildFunc(func(c *dojoBuilder.Config, bc dojoBuilder.BuildConfig, profilePath string) error {
+		close(started)
+		<-release
+		return fb.Build(c, bc, profilePath)
+	})
+	defer fb.Uninstall()
+
+	done := make(chan error)
+	go func() {
+		_, err := c1.Build(nil)
+		done <- err
+	}()
+
+	<-started
+	_, err := c2.Build(nil)
+	close(release)
+
+	if _, ok := err.(*dojoBuilder.ErrDestDirLocked); !ok {
+		t.Errorf("Build of the locked DestDir returned %v, want an ErrDestDirLocked", err)
+	}
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The release management of a DestDir locked by a build fails after
+// LockTimeout
+func TestReleaseManagementLockTimeout(t *testing.T) {
+	dir := t.TempDir()
+	c1 := newTestConfig(t, dir, "a")
+	c2 := newTestConfig(t, dir, "b")
+	c2.LockTimeout = -1
+
+	bc := c2.BuildConfigs["b"]
+	bc.Layout = dojoBuilder.VersionedLayout
+	bc.Retention = &dojoBuilder.Retention{KeepLast: 1}
+	c2.BuildConfigs["b"] = bc
+
+	fb := &buildertest.FakeBuilder{}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	dojoBuilder.SetBuildFunc(func(c *dojoBuilder.Config, bc dojoBuilder.BuildConfig, profilePath string) error {
+		close(started)
+		<-release
+		return fb.Build(c, bc, profilePath)
+	})
+	defer fb.Uninstall()
+
+	done := make(chan error)
+	go func() {
+		_, err := c1.Build(nil)
+		done <- err
+	}()
+
+	<-started
+	errs := map[string]error{
+		"Promote":     c2.Promote("b"),
+		"AbortCanary": c2.AbortCanary("b"),
+	}
+	_, errs["PruneReleases"] = c2.PruneReleases("b")
+	close(release)
+
+	for f, err := range errs {
+		if _, ok := err.(*dojoBuilder.ErrDestDirLocked); !ok {
+			t.Errorf("%s of the locked DestDir returned %v, want an ErrDestDirLocked", f, err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
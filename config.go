@@ -0,0 +1,62 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// LoadConfigFile reads a Config from the JSON file at path. Relative SrcDir,
+// DojoDir and DestDir are resolved from the directory of the file. A file of
+// an older SchemaVersion is migrated, printing warnings about the changes.
+func LoadConfigFile(path string) (c *Config, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	b, warnings, err := migrateConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s: %s\n", path, w)
+	}
+	if len(warnings) > 0 {
+		fmt.Printf("Warning: %s was migrated to schemaVersion %d, rewrite it to silence these warnings (dojobuilder upgrade)\n", path, ConfigSchemaVersion)
+	}
+
+	c = &Config{}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range []*string{&c.SrcDir, &c.DojoDir, &c.DestDir} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+
+	return
+}
+
+// WriteFile writes c as JSON into the file at path, with the current
+// ConfigSchemaVersion
+func (c *Config) WriteFile(path string) error {
+	fc := *c
+	fc.SchemaVersion = ConfigSchemaVersion
+
+	b, err := json.MarshalIndent(&fc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(b, '\n'), 0664)
+}
@@ -0,0 +1,78 @@
+package dojoBuilder_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func TestConfigFileResourceTags(t *testing.T) {
+	tags := &dojoBuilder.ResourceTags{
+		AMD:      []dojoBuilder.RegExp{`\.js$`},
+		CopyOnly: []dojoBuilder.RegExp{`^app/resources/`, `, \[/\]`},
+		Test:     []dojoBuilder.RegExp{`^app/tests/`},
+		Funcs: map[string]dojoBuilder.JS{
+			"miniExclude": `function(filename, mid){ return /\/demos\//.test(mid); }`,
+			"legacy":      `function(filename){ return false; }`,
+		},
+	}
+
+	dir := t.TempDir()
+	c := newTestConfig(t, dir, "a")
+	bc := c.BuildConfigs["a"]
+	bc.Packages[0].ResourceTags = tags
+	c.BuildConfigs["a"] = bc
+
+	path := filepath.Join(dir, "dojoBuilder.json")
+	if err := c.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	lc, err := dojoBuilder.LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := lc.BuildConfigs["a"].Packages[0].ResourceTags
+	if got == nil {
+		t.Fatal("The resource tags were not loaded")
+	}
+	if !reflect.DeepEqual(got, tags) {
+		t.Errorf("Loaded the resource tags %+v, want %+v", got, tags)
+	}
+}
+
+func TestDojoConfig(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestConfig(t, dir, "a")
+	c.DojoConfigRelPath = "app/dojoConfig.json"
+
+	bc := c.BuildConfigs["a"]
+	bc.Layout = dojoBuilder.VersionedLayout
+	c.BuildConfigs["a"] = bc
+
+	for p, content := range map[string]string{
+		"dojoBuilder.a.version":           "v0123456789\n",
+		"app/dojoConfig.json":             `{"top": true}`,
+		"v0123456789/app/dojoConfig.json": `{"versioned": true}`,
+	} {
+		p = filepath.Join(c.DestDir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dc, err := dojoBuilder.GetDojoConfig(c); err != nil || dc != `{"top": true}` {
+		t.Errorf("GetDojoConfig returned %q, %v", dc, err)
+	}
+	if dc, err := c.DojoConfig("a"); err != nil || dc != `{"versioned": true}` {
+		t.Errorf("DojoConfig returned %q, %v, want the one of the versioned release", dc, err)
+	}
+}
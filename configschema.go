@@ -0,0 +1,62 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ConfigSchemaVersion is the version of the config file format written by
+// WriteFile. The config files of older versions are migrated when loaded.
+const ConfigSchemaVersion = 1
+
+// configMigration upgrades the properties of a config file by one version,
+// returning warnings about what was changed
+type configMigration func(props map[string]json.RawMessage) (warnings []string, err error)
+
+// configMigrations upgrade the config files, the migration at index i from
+// version i to i+1. A format change has to add its migration here and
+// increment ConfigSchemaVersion.
+var configMigrations = []configMigration{
+	// Files written before the versioning have the version 1 format
+	func(props map[string]json.RawMessage) ([]string, error) {
+		return []string{"no schemaVersion, assuming the format of version 1"}, nil
+	},
+}
+
+// migrateConfig upgrades the JSON b of a config file to ConfigSchemaVersion.
+// It fails if the file was written by a newer version of dojoBuilder.
+func migrateConfig(b []byte) (_ []byte, warnings []string, err error) {
+	var props map[string]json.RawMessage
+	if err = json.Unmarshal(b, &props); err != nil {
+		return
+	}
+
+	var v int
+	if raw, ok := props["schemaVersion"]; ok {
+		if err = json.Unmarshal(raw, &v); err != nil {
+			return nil, nil, fmt.Errorf("Invalid config schemaVersion %s", raw)
+		}
+	}
+
+	if v > ConfigSchemaVersion {
+		return nil, nil, fmt.Errorf("Config schemaVersion %d is not supported (max %d), dojoBuilder needs to be upgraded", v, ConfigSchemaVersion)
+	} else if v == ConfigSchemaVersion {
+		return b, nil, nil
+	} else if v < 0 {
+		return nil, nil, fmt.Errorf("Invalid config schemaVersion %d", v)
+	}
+
+	for ; v < ConfigSchemaVersion; v++ {
+		w, err := configMigrations[v](props)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Migrating config schemaVersion %d to %d: %w", v, v+1, err)
+		}
+		warnings = append(warnings, w...)
+	}
+
+	props["schemaVersion"] = json.RawMessage(fmt.Sprint(ConfigSchemaVersion))
+
+	b, err = json.Marshal(props)
+
+	return b, warnings, err
+}
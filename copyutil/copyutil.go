@@ -0,0 +1,158 @@
+// Package copyutil copies files and directory trees, hard linking files
+// when possible.
+package copyutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CopyDir copies the directory src into dest, following symbolic links.
+func CopyDir(src string, dest string) (err error) {
+
+	sfi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	if sfi.Mode()&os.ModeSymlink != 0 {
+		linkSrc, err := linkTarget(src)
+		if err != nil {
+			return err
+		}
+		return CopyDir(linkSrc, dest)
+	} else if !sfi.IsDir() {
+		return fmt.Errorf("CopyFile cannot copy a file %s", src)
+	}
+
+	err = os.MkdirAll(dest, sfi.Mode())
+	if err != nil {
+		return err
+	}
+
+	directory, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer directory.Close()
+
+	objects, err := directory.Readdir(-1)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+
+		srcfilepointer := filepath.Join(src, obj.Name())
+
+		destfilepointer := filepath.Join(dest, obj.Name())
+
+		// The symbolic links to directories are copied as directories
+		isDir := obj.IsDir()
+		if obj.Mode()&os.ModeSymlink != 0 {
+			fi, err := os.Stat(srcfilepointer)
+			if err != nil {
+				return err
+			}
+			isDir = fi.IsDir()
+		}
+
+		if isDir {
+			err = CopyDir(srcfilepointer, destfilepointer)
+		} else {
+			err = CopyFile(srcfilepointer, destfilepointer)
+		}
+		if err != nil {
+			return
+		}
+
+	}
+	return
+}
+
+// linkTarget returns the path of the target of the symbolic link link,
+// relative targets being relative to the directory of link
+func linkTarget(link string) (string, error) {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+
+	return target, nil
+}
+
+// CopyFile copies a file from src to dest. If src and dest files exist, and are
+// the same, then return success. Otherise, attempt to create a hard link
+// between the two files. If that fail, copy the file contents from src to dest.
+func CopyFile(src, dest string) (err error) {
+	sfi, err := os.Lstat(src)
+	if err != nil {
+		return
+	}
+
+	if !sfi.Mode().IsRegular() {
+		if sfi.IsDir() {
+			return fmt.Errorf("CopyFile cannot copy a directory %s", src)
+		} else if sfi.Mode()&os.ModeSymlink != 0 {
+			linkSrc, err := linkTarget(src)
+			if err != nil {
+				return err
+			}
+			return CopyFile(linkSrc, dest)
+		} else {
+			return fmt.Errorf("CopyFile: non-regular source file %s (%q)", src, sfi.Mode().String())
+		}
+	}
+
+	dfi, err := os.Lstat(dest)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return
+		}
+	} else {
+		if !(dfi.Mode().IsRegular()) {
+			return fmt.Errorf("CopyFile: non-regular destination file %s (%q)", dfi.Name(), dfi.Mode().String())
+		}
+		if os.SameFile(sfi, dfi) {
+			return
+		}
+	}
+	if err = os.Link(src, dest); err == nil {
+		return
+	}
+	err = CopyContents(src, dest)
+	return
+}
+
+// CopyContents copies the contents of the file named src to the file named
+// by dest, never linking them. The file will be created if it does not already exist. If the
+// destination file exists, all it's contents will be replaced by the contents
+// of the source file.
+func CopyContents(src, dest string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return
+	}
+	defer func() {
+		cerr := out.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	if _, err = io.Copy(out, in); err != nil {
+		return
+	}
+	err = out.Sync()
+	return
+}
@@ -0,0 +1,147 @@
+package copyutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content into the file at path, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkFile fails if the file at path does not contain content
+func checkFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(b) != content {
+		t.Fatalf("%s contains %q, want %q", path, b, content)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	long := strings.Repeat(strings.Repeat("d", 100)+string(filepath.Separator), 30) + "module.js"
+
+	tests := []struct {
+		name     string
+		src      string // Path of the copied file, relative to the source dir
+		link     string // Target of the symbolic link created at src, if not empty
+		absolute bool   // The link target is made absolute, relative to the source dir
+		fails    bool
+	}{
+		{"plain file", "a.js", "", false, false},
+		{"unicode name", filepath.Join("été", "日本語 ü.js"), "", false, false},
+		{"long path", long, "", false, false},
+		{"relative symlink", filepath.Join("links", "rel.js"), filepath.Join("..", "target.js"), false, false},
+		{"absolute symlink", filepath.Join("links", "abs.js"), "target.js", true, false},
+		{"dangling symlink", filepath.Join("links", "dangling.js"), "missing.js", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src", tt.src)
+			dest := filepath.Join(dir, "dest", tt.src)
+			content := "define([], " + tt.name + ");"
+
+			if tt.link == "" {
+				writeFile(t, src, content)
+			} else {
+				target := tt.link
+				if tt.absolute {
+					target = filepath.Join(dir, "src", tt.link)
+				}
+				if !tt.fails {
+					writeFile(t, filepath.Join(dir, "src", "target.js"), content)
+				}
+				if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, src); err != nil {
+					t.Skip(err)
+				}
+			}
+
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := CopyFile(src, dest)
+			if tt.fails {
+				if err == nil {
+					t.Fatal("CopyFile succeeded, want an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			checkFile(t, dest, content)
+
+			if fi, err := os.Lstat(dest); err != nil {
+				t.Fatal(err)
+			} else if !fi.Mode().IsRegular() {
+				t.Fatalf("%s is not a regular file: %s", dest, fi.Mode())
+			}
+		})
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+
+	files := map[string]string{
+		"app/main.js":           "main",
+		"app/été/日本語.html":      "unicode",
+		"lib/nested/deep/x.css": "deep",
+	}
+	for p, content := range files {
+		writeFile(t, filepath.Join(src, filepath.FromSlash(p)), content)
+	}
+
+	if err := os.Symlink(filepath.Join("..", "lib", "nested"), filepath.Join(src, "app", "linkedDir")); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "app", "main.js"), filepath.Join(src, "lib", "linked.js")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyDir(src, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	for p, content := range files {
+		checkFile(t, filepath.Join(dest, filepath.FromSlash(p)), content)
+	}
+	checkFile(t, filepath.Join(dest, "app", "linkedDir", "deep", "x.css"), "deep")
+	checkFile(t, filepath.Join(dest, "lib", "linked.js"), "main")
+}
+
+func TestCopyContentsNeverLinks(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.js")
+	dest := filepath.Join(dir, "dest.js")
+	writeFile(t, src, "source")
+
+	if err := CopyContents(src, dest); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dest, "changed")
+
+	checkFile(t, src, "source")
+}
@@ -0,0 +1,83 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DojoBootLayer is the layer of the dojo loader, the only one a custom base
+// applies to
+const DojoBootLayer = "dojo/dojo"
+
+// SetCustomBase makes the boot layer DojoBootLayer of bc a custom base: the
+// loader with only the modules include (the entry modules of the application
+// and the dojo/_base modules it uses) instead of the whole dojo base. The
+// other layers exclude the boot layer so that its modules are not
+// duplicated.
+func (bc *BuildConfig) SetCustomBase(include ...string) {
+	layers := make(map[string]Layer, len(bc.Layers)+1)
+
+	for mid, l := range bc.Layers {
+		if mid != DojoBootLayer && !containsString(l.Exclude, DojoBootLayer) {
+			l.Exclude = append(append([]string(nil), l.Exclude...), DojoBootLayer)
+		}
+		layers[mid] = l
+	}
+
+	boot := layers[DojoBootLayer]
+	boot.Boot = true
+	boot.CustomBase = true
+
+	boot.Include = append([]string(nil), boot.Include...)
+	for _, mid := range include {
+		if !containsString(boot.Include, mid) {
+			boot.Include = append(boot.Include, mid)
+		}
+	}
+
+	layers[DojoBootLayer] = boot
+	bc.Layers = layers
+}
+
+// customBaseWarnings returns the known pitfalls of the custom base layers of
+// bc
+func customBaseWarnings(bc BuildConfig) (warnings []string) {
+	boot, ok := bc.Layers[DojoBootLayer]
+	if !ok || !boot.CustomBase {
+		for mid, l := range bc.Layers {
+			if l.CustomBase {
+				warnings = append(warnings, fmt.Sprintf("customBase of layer %s has no effect, it only applies to the %s layer", mid, DojoBootLayer))
+			}
+		}
+		sort.Strings(warnings)
+		return
+	}
+
+	if !boot.Boot {
+		warnings = append(warnings, fmt.Sprintf("the custom base layer %s is not a boot layer, it does not include the loader", DojoBootLayer))
+	}
+
+	for _, mid := range boot.Include {
+		if mid == "dojo/main" || mid == "dojo" {
+			warnings = append(warnings, fmt.Sprintf("the custom base layer %s includes %s, the whole dojo base", DojoBootLayer, mid))
+		}
+	}
+
+	for mid, l := range bc.Layers {
+		if mid == DojoBootLayer {
+			continue
+		}
+
+		if l.CustomBase {
+			warnings = append(warnings, fmt.Sprintf("customBase of layer %s has no effect, it only applies to the %s layer", mid, DojoBootLayer))
+		}
+
+		if !containsString(l.Exclude, DojoBootLayer) {
+			warnings = append(warnings, fmt.Sprintf("layer %s does not exclude the custom base %s, it duplicates its modules", mid, DojoBootLayer))
+		}
+	}
+
+	sort.Strings(warnings)
+
+	return
+}
@@ -0,0 +1,214 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	cssClassRegexp  = regexp.MustCompile(`\.(-?[_a-zA-Z][_a-zA-Z0-9-]*)`)
+	htmlClassRegexp = regexp.MustCompile(`class\s*=\s*\\?["']([^"'\\]*)`)
+	jsStringRegexp  = regexp.MustCompile(`'((?:[^'\\\n]|\\.)*)'|"((?:[^"\\\n]|\\.)*)"`)
+	classNameRegexp = regexp.MustCompile(`^-?[_a-zA-Z][_a-zA-Z0-9-]*$`)
+	cssQuotedRegexp = regexp.MustCompile(`(?s)"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`)
+)
+
+// cssStateSuffixes are appended at runtime by dijit/_CssStateMixin to the
+// baseClass of the widgets
+var cssStateSuffixes = []string{"Hover", "Active", "Focused", "Disabled", "ReadOnly", "Checked", "Selected", "Error", "Incomplete", "Opened", "Mixed"}
+
+// DeadCSSReport lists the CSS selectors of the stylesheets of a build which
+// are likely dead: they use classes found in no layer string, interned
+// template or HTML file of the application. Classes built at runtime other
+// than the dijit state ones are missed, so the report has to be reviewed.
+type DeadCSSReport struct {
+	Selectors map[string][]string // Stylesheet (relative to DestDir) => likely dead selectors
+}
+
+// DeadCSS analyses the stylesheets of the build config name output in
+// DestDir: the main stylesheet of its Themes and the ones loaded by the
+// layer modules through a css! plugin. The classes used are the ones of the
+// string literals of the layers, interned templates included, and of the
+// HTMLFiles of the build config.
+func (c *Config) DeadCSS(name string) (*DeadCSSReport, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	used := make(map[string]bool)
+	addClasses := func(s string) {
+		for _, cl := range strings.Fields(s) {
+			if classNameRegexp.MatchString(cl) {
+				used[cl] = true
+			}
+		}
+	}
+
+	layers := c.builtLayers(bc)
+	stylesheets := make(map[string]bool)
+
+	for _, l := range layers {
+		if l.Err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(l.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range jsStringRegexp.FindAllSubmatch(b, -1) {
+			addClasses(string(m[1]) + " " + string(m[2]))
+		}
+
+		for _, m := range htmlClassRegexp.FindAllSubmatch(b, -1) {
+			addClasses(string(m[1]))
+		}
+	}
+
+	ls, err := c.layerStylesheets(bc, layers)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ls {
+		stylesheets[p] = true
+	}
+
+	for _, h := range bc.HTMLFiles {
+		b, err := ioutil.ReadFile(filepath.Join(c.SrcDir, h))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range htmlClassRegexp.FindAllSubmatch(b, -1) {
+			addClasses(string(m[1]))
+		}
+	}
+
+	for _, t := range bc.Themes {
+		dest := t.Dest
+		if dest == "" {
+			dest = "themes/" + t.Name
+		}
+		stylesheets[filepath.Join(c.DestDir, dest, t.Name+".css")] = true
+	}
+
+	r := &DeadCSSReport{Selectors: make(map[string][]string)}
+
+	for p := range stylesheets {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(c.DestDir, p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sel := range cssSelectors(string(b)) {
+			if !selectorUsed(sel, used) {
+				r.Selectors[filepath.ToSlash(rel)] = append(r.Selectors[filepath.ToSlash(rel)], sel)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// selectorUsed returns false if sel has a class which is not used
+func selectorUsed(sel string, used map[string]bool) bool {
+	// The attribute values may contain dots
+	sel = cssQuotedRegexp.ReplaceAllString(sel, `""`)
+
+	for _, m := range cssClassRegexp.FindAllStringSubmatch(sel, -1) {
+		if !classUsed(m[1], used) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func classUsed(class string, used map[string]bool) bool {
+	if used[class] {
+		return true
+	}
+
+	for _, s := range cssStateSuffixes {
+		if i := strings.Index(class, s); i > 0 && used[class[:i]] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cssSelectors returns the selectors of the style rules of css, the ones
+// nested in at-rules like @media included
+func cssSelectors(css string) (selectors []string) {
+	css = cssCommentRegexp.ReplaceAllString(css, "")
+
+	depth, start, skip := 0, 0, -1
+	for i := 0; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			prelude := strings.TrimSpace(css[start:i])
+			if skip < 0 {
+				if strings.HasPrefix(prelude, "@") {
+					// The rules of @keyframes, @font-face... have no selectors
+					if !strings.HasPrefix(prelude, "@media") && !strings.HasPrefix(prelude, "@supports") {
+						skip = depth
+					}
+				} else {
+					for _, s := range strings.Split(prelude, ",") {
+						if s = strings.Join(strings.Fields(s), " "); s != "" {
+							selectors = append(selectors, s)
+						}
+					}
+					skip = depth
+				}
+			}
+			depth++
+			start = i + 1
+		case '}':
+			depth--
+			if depth == skip {
+				skip = -1
+			}
+			start = i + 1
+		case ';':
+			if skip < 0 {
+				start = i + 1
+			}
+		}
+	}
+
+	return
+}
+
+// Print writes a human readable version of the report to w
+func (r *DeadCSSReport) Print(w io.Writer) {
+	stylesheets := make([]string, 0, len(r.Selectors))
+	for s := range r.Selectors {
+		stylesheets = append(stylesheets, s)
+	}
+	sort.Strings(stylesheets)
+
+	if len(stylesheets) == 0 {
+		fmt.Fprintln(w, "No dead CSS found")
+	}
+
+	for _, s := range stylesheets {
+		fmt.Fprintf(w, "Stylesheet %s: %d likely dead selectors\n", s, len(r.Selectors[s]))
+		for _, sel := range r.Selectors[s] {
+			fmt.Fprintf(w, "  %s\n", sel)
+		}
+	}
+}
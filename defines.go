@@ -0,0 +1,120 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultDefinesModule is the module id of the module of the Defines of a
+// build config when its DefinesModule is not set
+const DefaultDefinesModule = "defines"
+
+// definesModule returns the source of the module returning defines, which
+// adds their boolean values to has() for the unbuilt sources
+func definesModule(defines map[string]interface{}) (string, error) {
+	j, err := json.MarshalIndent(defines, "\t", "\t")
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(defines))
+	for n, v := range defines {
+		if _, ok := v.(bool); ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	var s strings.Builder
+	s.WriteString("// Generated by dojoBuilder from the Defines of the build config\n")
+	s.WriteString("define([\"dojo/has\"], function(has){\n")
+	fmt.Fprintf(&s, "\tvar defines = %s;\n", j)
+	for _, n := range names {
+		fmt.Fprintf(&s, "\thas.add(%q, defines[%q]);\n", n, n)
+	}
+	s.WriteString("\treturn defines;\n});\n")
+
+	return s.String(), nil
+}
+
+// applyDefines writes the module of the Defines of bc into the stub package
+// of the profiles directory and maps the DefinesModule to it. The boolean
+// Defines become static has-features as well, so that the optimizer removes
+// the code they disable.
+func (c *Config) applyDefines(name string, bc *BuildConfig) (err error) {
+	if len(bc.Defines) == 0 {
+		return
+	}
+
+	mid := bc.DefinesModule
+	if mid == "" {
+		mid = DefaultDefinesModule
+	}
+
+	features := make(map[string]Feature, len(bc.StaticHasFeatures)+len(bc.Defines))
+	for n, f := range bc.StaticHasFeatures {
+		features[n] = f
+	}
+	for n, v := range bc.Defines {
+		if b, ok := v.(bool); ok {
+			if f, ok := features[n]; ok && bool(f) != b {
+				return fmt.Errorf("Define '%s' conflicts with the static has-feature of the same name", n)
+			}
+			features[n] = Feature(b)
+		}
+	}
+	bc.StaticHasFeatures = features
+
+	src, err := definesModule(bc.Defines)
+	if err != nil {
+		return
+	}
+
+	stubsDir := filepath.Join(c.profilesDir(), c.workName(stubsPackageName, name))
+	if err = os.MkdirAll(stubsDir, 0754); err != nil {
+		return
+	}
+
+	stub := stubName(mid)
+	if err = ioutil.WriteFile(filepath.Join(stubsDir, stub+".js"), []byte(src), 0664); err != nil {
+		return
+	}
+
+	m := make(map[string]map[string]string, len(bc.Map)+1)
+	for k, v := range bc.Map {
+		m[k] = v
+	}
+
+	star := make(map[string]string, len(m["*"])+1)
+	for k, v := range m["*"] {
+		star[k] = v
+	}
+	star[mid] = stubsPackageName + "/" + stub
+
+	m["*"] = star
+	bc.Map = m
+
+	for _, p := range bc.Packages {
+		if p.Name == stubsPackageName {
+			return
+		}
+	}
+
+	stubsLocation, err := filepath.Rel(c.SrcDir, stubsDir)
+	if err != nil {
+		return
+	}
+
+	bc.Packages = append(append([]Package{}, bc.Packages...), Package{
+		Name:         stubsPackageName,
+		Location:     filepath.ToSlash(stubsLocation),
+		DestLocation: "profiles/" + c.workName(stubsPackageName, name),
+	})
+
+	return
+}
@@ -0,0 +1,266 @@
+package dojoBuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// deployReportedFilesMax is the max number of the files not deployed printed
+const deployReportedFilesMax = 20
+
+// Deployer deploys the release of the build config name output in the
+// DestDir of c, e.g. to a web server or an object storage
+type Deployer interface {
+	Deploy(c *Config, name string) error
+}
+
+// ContextDeployer is a Deployer stopping its deployment when the context is
+// done, see DeployTarget.Timeout
+type ContextDeployer interface {
+	Deployer
+	DeployContext(ctx context.Context, c *Config, name string) error
+}
+
+// RollbackDeployer is a Deployer able to restore the deployment preceding
+// its last Deploy, for the DeployAllOrNothing build configs
+type RollbackDeployer interface {
+	Deployer
+	Rollback(c *Config, name string) error
+}
+
+// DeployTarget is a deployment target of a build config
+type DeployTarget struct {
+	Name        string // Name of the target in the results and logs
+	Deployer    Deployer
+	HealthCheck *HealthCheck  // Check of the deployment, failing it when the release is not served correctly (optional)
+	Timeout     time.Duration // Max duration of the deployment, the HealthCheck excepted (optional). Only a ContextDeployer is stopped then, the others go on in the background.
+}
+
+// DeployResult is the outcome of the deployment of a build to a target
+type DeployResult struct {
+	Target      string
+	Err         error // Deployment error, nil on success
+	Duration    time.Duration
+	RolledBack  bool     // The deployment was rolled back as a target failed
+	RollbackErr error    // Error of the rollback, if any
+	FailedFiles []string // Paths, relative to DestDir, of the files not deployed, if known, see ErrDeployPartial
+
+	deployed bool // Deploy succeeded, the HealthCheck may have failed
+}
+
+// CommandDeployer deploys with a command, e.g. rsync or "aws s3 sync". The
+// command is run in DestDir with its path in the DOJOBUILDER_RELEASE_DIR
+// environment variable, the build id in BuildIDEnv and its RateLimit in
+// DOJOBUILDER_RATE_LIMIT. It is killed once the Timeout of its target is
+// exceeded.
+type CommandDeployer struct {
+	Command         []string          // Command and its arguments
+	RollbackCommand []string          // Command restoring the previous deployment (optional)
+	Secrets         map[string]string // Environment variable => name of the secret it is set to, e.g. "AWS_SECRET_ACCESS_KEY" (optional)
+	RateLimit       int               // Max transfer rate (KB/s) replacing "{rateLimit}" in the arguments, e.g. "rsync --bwlimit={rateLimit}" (optional)
+}
+
+func (d CommandDeployer) Deploy(c *Config, name string) error {
+	return d.run(context.Background(), c, d.Command)
+}
+
+func (d CommandDeployer) DeployContext(ctx context.Context, c *Config, name string) error {
+	return d.run(ctx, c, d.Command)
+}
+
+// Rollback runs the RollbackCommand, failing if there is none
+func (d CommandDeployer) Rollback(c *Config, name string) error {
+	if len(d.RollbackCommand) == 0 {
+		return errors.New("No rollback command")
+	}
+
+	return d.run(context.Background(), c, d.RollbackCommand)
+}
+
+func (d CommandDeployer) run(ctx context.Context, c *Config, command []string) error {
+	if len(command) == 0 {
+		return errors.New("No deploy command")
+	}
+
+	rate := strconv.Itoa(d.RateLimit)
+	r := strings.NewReplacer("{rateLimit}", rate)
+
+	args := make([]string, len(command)-1)
+	for i, a := range command[1:] {
+		args[i] = r.Replace(a)
+	}
+
+	secrets, err := c.secretValues(d.Secrets)
+	if err != nil {
+		return err
+	}
+
+	proxyEnv, err := c.proxyEnv()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(command[0], args...)
+	cmd.Dir = c.DestDir
+	cmd.Env = append(os.Environ(), "DOJOBUILDER_RELEASE_DIR="+c.DestDir, BuildIDEnv+"="+c.buildID, "DOJOBUILDER_RATE_LIMIT="+rate)
+	cmd.Env = append(cmd.Env, proxyEnv...)
+	for env, v := range secrets {
+		cmd.Env = append(cmd.Env, env+"="+v)
+	}
+
+	// The command may print its credentials
+	stdout, stderr := newScrubWriter(c.stdout()), newScrubWriter(c.stderr())
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// rsync and the cloud CLIs run child processes
+			killProcessTree(cmd.Process)
+		case <-done:
+		}
+	}()
+
+	err = cmd.Wait()
+	close(done)
+	stdout.Close()
+	stderr.Close()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	} else if err != nil {
+		return fmt.Errorf("%s: %s", strings.Join(command, " "), err)
+	}
+
+	return nil
+}
+
+// deployTarget deploys the release of the build config name to t, stopping
+// after its Timeout
+func (c *Config) deployTarget(name string, t DeployTarget) (err error) {
+	if t.Timeout <= 0 {
+		return t.Deployer.Deploy(c, name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.Timeout)
+	defer cancel()
+
+	if cd, ok := t.Deployer.(ContextDeployer); ok {
+		err = cd.DeployContext(ctx, c, name)
+	} else {
+		done := make(chan error, 1)
+		go func() {
+			done <- t.Deployer.Deploy(c, name)
+		}()
+
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return &ErrDeployTimeout{Target: t.Name, Timeout: t.Timeout, Err: err}
+	}
+
+	return
+}
+
+// deploy deploys the release of the build config name to the Deploy
+// targets of bc in parallel, recording their results into r. When a target
+// fails, the deployed ones are rolled back if bc.DeployAllOrNothing is set,
+// including the ones whose HealthCheck failed.
+func (c *Config) deploy(name string, bc BuildConfig, r *BuildResult) error {
+	r.Deployments = make([]DeployResult, len(bc.Deploy))
+
+	var wg sync.WaitGroup
+	for i, t := range bc.Deploy {
+		wg.Add(1)
+		go func(i int, t DeployTarget) {
+			defer wg.Done()
+
+			start := time.Now()
+			d := DeployResult{Target: t.Name}
+
+			if d.Err = c.deployTarget(name, t); d.Err == nil {
+				d.deployed = true
+				if t.HealthCheck != nil {
+					if err := t.HealthCheck.check(c); err != nil {
+						d.Err = fmt.Errorf("Health check failed: %s", err)
+					}
+				}
+			}
+			d.Err = scrubError(d.Err)
+
+			var pe *ErrDeployPartial
+			if errors.As(d.Err, &pe) {
+				d.FailedFiles = pe.Files()
+			}
+
+			d.Duration = time.Since(start)
+			r.Deployments[i] = d
+		}(i, t)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, d := range r.Deployments {
+		if d.Err != nil {
+			failed = append(failed, d.Target)
+			fmt.Fprintf(c.stdout(), "Deployment of %s to %s failed: %s\n", name, d.Target, d.Err)
+
+			if n := len(d.FailedFiles); n > 0 {
+				files := d.FailedFiles
+				if n > deployReportedFilesMax {
+					files = files[:deployReportedFilesMax]
+				}
+				fmt.Fprintf(c.stdout(), "  Not deployed: %s", strings.Join(files, ", "))
+				if n > len(files) {
+					fmt.Fprintf(c.stdout(), " and %d more", n-len(files))
+				}
+				fmt.Fprintln(c.stdout())
+			}
+		} else {
+			fmt.Fprintf(c.stdout(), "Deployed %s to %s\n", name, d.Target)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	if bc.DeployAllOrNothing {
+		for i, t := range bc.Deploy {
+			d := &r.Deployments[i]
+			if !d.deployed {
+				continue
+			}
+
+			rd, ok := t.Deployer.(RollbackDeployer)
+			if !ok {
+				d.RollbackErr = fmt.Errorf("%s cannot be rolled back", t.Name)
+			} else if d.RollbackErr = scrubError(rd.Rollback(c, name)); d.RollbackErr == nil {
+				d.RolledBack = true
+			}
+
+			if d.RollbackErr != nil {
+				fmt.Fprintf(c.stdout(), "Warning: cannot roll back the deployment to %s: %s\n", t.Name, d.RollbackErr)
+			}
+		}
+	}
+
+	return fmt.Errorf("Deployment failed for %s", strings.Join(failed, ", "))
+}
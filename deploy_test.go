@@ -0,0 +1,306 @@
+package dojoBuilder_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tbaud0n/dojoBuilder"
+	"github.com/tbaud0n/dojoBuilder/buildertest"
+)
+
+const testSecret = "s3cr3t-t0ken"
+
+// newTestConfig returns a config building the app/main layer of each of the
+// build configs names from sources written into dir
+func newTestConfig(t *testing.T, dir string, names ...string) *dojoBuilder.Config {
+	t.Helper()
+
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "app", "main.js"), []byte("define([], function(){});\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &dojoBuilder.Config{
+		BuildMode:    true,
+		SrcDir:       src,
+		DestDir:      filepath.Join(dir, "dest"),
+		Output:       ioutil.Discard,
+		BuildConfigs: make(map[string]dojoBuilder.BuildConfig),
+	}
+
+	for _, n := range names {
+		c.BuildConfigs[n] = dojoBuilder.BuildConfig{
+			Packages: []dojoBuilder.Package{{Name: "app", Location: "app"}},
+			Layers:   map[string]dojoBuilder.Layer{"app/main": {}},
+		}
+	}
+
+	return c
+}
+
+func TestDeploySecrets(t *testing.T) {
+	dir := t.TempDir()
+	secrets := filepath.Join(dir, "secrets")
+	if err := os.MkdirAll(secrets, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(secrets, "TOKEN"), []byte(testSecret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		http.Error(w, "bad token "+auth, http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	c := newTestConfig(t, dir, "a")
+	c.Output = &out
+	c.Secrets = dojoBuilder.FileSecrets(secrets)
+
+	bc := c.BuildConfigs["a"]
+	bc.Deploy = []dojoBuilder.DeployTarget{
+		{Name: "command", Deployer: dojoBuilder.CommandDeployer{
+			Command: []string{"sh", "-c", `echo "token: $DEPLOY_TOKEN"`},
+			Secrets: map[string]string{"DEPLOY_TOKEN": "TOKEN"},
+		}},
+		{Name: "webhook", Deployer: dojoBuilder.WebhookDeployer{
+			URL:           srv.URL,
+			HeaderSecrets: map[string]string{"Authorization": "TOKEN"},
+		}},
+	}
+	c.BuildConfigs["a"] = bc
+
+	fb := &buildertest.FakeBuilder{}
+	fb.Install()
+	defer fb.Uninstall()
+
+	results, err := c.Build(nil)
+	if err == nil {
+		t.Fatal("The deployment to the failing webhook succeeded")
+	}
+
+	if auth != testSecret {
+		t.Errorf("The webhook received the Authorization %q, want the secret", auth)
+	}
+
+	if !strings.Contains(out.String(), "token: ********") || strings.Contains(out.String(), testSecret) {
+		t.Errorf("The secret is not scrubbed from the deploy output %q", out.String())
+	}
+
+	deployments := results[0].Deployments
+	if len(deployments) != 2 {
+		t.Fatalf("%d deployments, want 2", len(deployments))
+	}
+	if deployments[0].Err != nil {
+		t.Errorf("Deployment to %s: %s", deployments[0].Target, deployments[0].Err)
+	}
+	if werr := deployments[1].Err; werr == nil || !strings.Contains(werr.Error(), "403") || strings.Contains(werr.Error(), testSecret) {
+		t.Errorf("The webhook error %v is not the scrubbed 403", werr)
+	}
+}
+
+func TestEnvSecrets(t *testing.T) {
+	os.Setenv("DOJOBUILDER_TEST_TOKEN", testSecret)
+	defer os.Unsetenv("DOJOBUILDER_TEST_TOKEN")
+
+	if v, err := dojoBuilder.EnvSecrets("DOJOBUILDER_TEST_").Secret("TOKEN"); err != nil || v != testSecret {
+		t.Errorf("EnvSecrets returned %q, %v", v, err)
+	}
+
+	if _, err := dojoBuilder.EnvSecrets("DOJOBUILDER_TEST_").Secret("MISSING"); err == nil {
+		t.Error("EnvSecrets returned an unset variable")
+	}
+
+	if _, err := dojoBuilder.FileSecrets(t.TempDir()).Secret("../TOKEN"); err == nil {
+		t.Error("FileSecrets read a file out of its directory")
+	}
+}
+
+func TestDeployProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	var out bytes.Buffer
+	c := newTestConfig(t, t.TempDir(), "a")
+	c.Output = &out
+	c.Proxy = proxy.URL
+
+	bc := c.BuildConfigs["a"]
+	bc.Deploy = []dojoBuilder.DeployTarget{
+		{Name: "command", Deployer: dojoBuilder.CommandDeployer{Command: []string{"sh", "-c", `echo "proxy: $HTTPS_PROXY"`}}},
+		{Name: "webhook", Deployer: dojoBuilder.WebhookDeployer{URL: "http://deploy.example.invalid/hook"}},
+	}
+	c.BuildConfigs["a"] = bc
+
+	fb := &buildertest.FakeBuilder{}
+	fb.Install()
+	defer fb.Uninstall()
+
+	if _, err := c.Build(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if proxied != "http://deploy.example.invalid/hook" {
+		t.Errorf("The proxy received %q, want the webhook url", proxied)
+	}
+	if !strings.Contains(out.String(), "proxy: "+proxy.URL) {
+		t.Errorf("The deploy command has no proxy: %q", out.String())
+	}
+}
+
+func TestDeployTimeout(t *testing.T) {
+	c := newTestConfig(t, t.TempDir(), "a")
+
+	bc := c.BuildConfigs["a"]
+	bc.Deploy = []dojoBuilder.DeployTarget{
+		{Name: "command", Deployer: dojoBuilder.CommandDeployer{Command: []string{"sleep", "10"}}, Timeout: 200 * time.Millisecond},
+	}
+	c.BuildConfigs["a"] = bc
+
+	fb := &buildertest.FakeBuilder{}
+	fb.Install()
+	defer fb.Uninstall()
+
+	start := time.Now()
+	results, err := c.Build(nil)
+	if err == nil {
+		t.Fatal("The deployment exceeding its timeout succeeded")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("The deploy command was not killed after its timeout, the build took %s", d)
+	}
+
+	var te *dojoBuilder.ErrDeployTimeout
+	if derr := results[0].Deployments[0].Err; !errors.As(derr, &te) || te.Target != "command" {
+		t.Errorf("The deployment error %v is not an ErrDeployTimeout of the target", derr)
+	}
+}
+
+func TestUploadDeployer(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		uploaded = make(map[string]string)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".js") {
+			http.Error(w, "no scripts", http.StatusForbidden)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		uploaded[r.URL.Path] = string(b)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := newTestConfig(t, dir, "a")
+
+	for name, content := range map[string]string{"index.html": "<html></html>", "app/main.js": "define([], 1);"} {
+		p := filepath.Join(c.DestDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := dojoBuilder.UploadDeployer{BaseURL: srv.URL + "/release/", RateLimit: 1 << 20}.Deploy(c, "a")
+
+	var pe *dojoBuilder.ErrDeployPartial
+	if !errors.As(err, &pe) {
+		t.Fatalf("The upload returned %v, want an ErrDeployPartial", err)
+	}
+	if files := pe.Files(); len(files) != 1 || files[0] != "app/main.js" {
+		t.Errorf("The files not uploaded are %v, want app/main.js", files)
+	}
+	if uploaded["/release/index.html"] != "<html></html>" {
+		t.Errorf("index.html was not uploaded: %v", uploaded)
+	}
+}
+
+func TestUploadDeployerRelease(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		uploaded = make(map[string]http.Header)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		uploaded[r.URL.Path] = r.Header
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := newTestConfig(t, dir, "a")
+
+	bc := c.BuildConfigs["a"]
+	bc.Layout = dojoBuilder.VersionedLayout
+	bc.ContentTypes = dojoBuilder.ContentTypes{".js": {CacheControl: "public, max-age=31536000, immutable"}}
+	c.BuildConfigs["a"] = bc
+
+	for _, name := range []string{
+		"dojoBuilder.a.version",
+		"dojoBuilder.a.versions",
+		"dojoBuilder.a.cachebust",
+		dojoBuilder.LockFileName,
+		"v0123456789/app/main.js",
+		"v0123456789/" + dojoBuilder.MetadataFileName,
+		"v9876543210/app/main.js",
+	} {
+		content := "define([], 1);"
+		if name == "dojoBuilder.a.version" {
+			content = "v0123456789\n"
+		}
+
+		p := filepath.Join(c.DestDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := (dojoBuilder.UploadDeployer{BaseURL: srv.URL}).Deploy(c, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uploaded) != 1 {
+		t.Errorf("Uploaded %v, want the files of the release only", uploaded)
+	}
+	h, ok := uploaded["/v0123456789/app/main.js"]
+	if !ok {
+		t.Fatalf("The release was not uploaded: %v", uploaded)
+	}
+	if ct := h.Get("Content-Type"); ct != "text/javascript; charset=utf-8" {
+		t.Errorf("Uploaded app/main.js as %s", ct)
+	}
+	if cc := h.Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Errorf("Uploaded app/main.js with the Cache-Control %q", cc)
+	}
+}
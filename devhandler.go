@@ -0,0 +1,72 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Paths of the endpoints of the dev handler
+const (
+	DevStatusPath  = "/dojoBuilder/status"
+	DevOverlayPath = "/dojoBuilder/overlay.js"
+)
+
+// devOverlayScript polls the build status and overlays the build error on
+// the page while the last build is failing
+const devOverlayScript = `(function(){
+	var overlay = null;
+	function update(status){
+		if(!status.error){
+			if(overlay){ overlay.parentNode.removeChild(overlay); overlay = null; }
+			return;
+		}
+		if(!overlay){
+			overlay = document.createElement("pre");
+			overlay.style.cssText = "position:fixed;top:0;left:0;right:0;bottom:0;margin:0;padding:2em;z-index:2147483647;" +
+				"overflow:auto;background:rgba(0,0,0,.85);color:#ff6b6b;font:14px/1.4 monospace;white-space:pre-wrap";
+			document.body.appendChild(overlay);
+		}
+		overlay.textContent = "dojoBuilder build failed, the assets served are stale:\n\n" + status.error;
+	}
+	function poll(){
+		var xhr = new XMLHttpRequest();
+		xhr.open("GET", "` + DevStatusPath + `");
+		xhr.onload = function(){ try{ update(JSON.parse(xhr.responseText)); }catch(e){} };
+		xhr.send();
+	}
+	poll();
+	setInterval(poll, 2000);
+})();
+`
+
+// DevHandler returns an http.Handler serving DestDir for development. If
+// overlay is true it also serves DevOverlayPath, a script pages include to
+// get the error of the last failed build overlaid on them, and the build
+// status it polls at DevStatusPath.
+func (w *Watcher) DevHandler(overlay bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(w.Config.DestDir)))
+
+	if !overlay {
+		return mux
+	}
+
+	mux.HandleFunc(DevOverlayPath, func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/javascript")
+		io.WriteString(rw, devOverlayScript)
+	})
+
+	mux.HandleFunc(DevStatusPath, func(rw http.ResponseWriter, r *http.Request) {
+		status := map[string]string{}
+		if err := w.LastError(); err != nil {
+			status["error"] = err.Error()
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(rw).Encode(status)
+	})
+
+	return mux
+}
@@ -0,0 +1,190 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// doctorMinFreeSpace is the free space under which the DestDir file system
+// is reported as too small
+const doctorMinFreeSpace = 100 << 20
+
+// DoctorCheck is the result of one check of Config.Doctor
+type DoctorCheck struct {
+	Name    string
+	OK      bool
+	Message string
+}
+
+// DoctorReport is the result of Config.Doctor
+type DoctorReport struct {
+	Checks []DoctorCheck
+}
+
+// OK returns true if every check succeeded
+func (r *DoctorReport) OK() bool {
+	for _, ch := range r.Checks {
+		if !ch.OK {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Print writes a human readable version of the report to w
+func (r *DoctorReport) Print(w io.Writer) {
+	for _, ch := range r.Checks {
+		status := "OK"
+		if !ch.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "[%s] %s: %s\n", status, ch.Name, ch.Message)
+	}
+}
+
+func (r *DoctorReport) add(name string, ok bool, format string, a ...interface{}) {
+	r.Checks = append(r.Checks, DoctorCheck{Name: name, OK: ok, Message: fmt.Sprintf(format, a...)})
+}
+
+// Doctor checks the environment needed to build: bash, node or java, the
+// build script, the dojo checkout, the free space and the write permissions.
+func (c *Config) Doctor() *DoctorReport {
+	r := &DoctorReport{}
+
+	if p, err := exec.LookPath("bash"); err != nil {
+		r.add("bash", false, "not found")
+	} else {
+		r.add("bash", true, "%s", p)
+	}
+
+	nodeVersion, nodeErr := commandVersion("node", "--version")
+	javaVersion, javaErr := commandVersion("java", "-version")
+
+	switch {
+	case strings.HasPrefix(c.Bin, "node"):
+		r.add("node", nodeErr == nil, "%s", firstNonEmpty(nodeVersion, "not found"))
+	case c.Bin == "java":
+		r.add("java", javaErr == nil, "%s", firstNonEmpty(javaVersion, "not found"))
+	default:
+		r.add("node", true, "%s", firstNonEmpty(nodeVersion, "not found"))
+		r.add("java", true, "%s", firstNonEmpty(javaVersion, "not found"))
+		if nodeErr != nil && javaErr != nil {
+			r.add("javascript runtime", false, "neither node nor java found")
+		}
+	}
+
+	buildScript := filepath.Join(c.ToolkitDir(), "util", "buildscripts", "build.sh")
+	if fi, err := os.Stat(buildScript); err != nil {
+		r.add("build script", false, "%s not found", buildScript)
+	} else if fi.Mode()&0111 == 0 {
+		r.add("build script", false, "%s is not executable", buildScript)
+	} else {
+		r.add("build script", true, "%s", buildScript)
+	}
+
+	missing := []string{}
+	for _, f := range []string{"dojo/dojo.js", "dojo/_base/kernel.js", "util/build/main.js"} {
+		if _, err := os.Stat(filepath.Join(c.ToolkitDir(), f)); err != nil {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		r.add("dojo checkout", false, "missing %s", strings.Join(missing, ", "))
+	} else if v, err := c.DojoVersion(); err != nil {
+		r.add("dojo checkout", false, "%s", err)
+	} else {
+		r.add("dojo checkout", true, "dojo %s", v)
+	}
+
+	if c.ToolkitChecksums != "" {
+		if diffs, err := c.VerifyToolkit(); err != nil {
+			r.add("dojo checksums", false, "%s", err)
+		} else if len(diffs) > 0 {
+			r.add("dojo checksums", false, "%d files differ from %s", len(diffs), c.ToolkitChecksums)
+		} else {
+			r.add("dojo checksums", true, "%s", c.ToolkitChecksums)
+		}
+	}
+
+	if free, err := freeSpace(c.DestDir); err != nil {
+		r.add("disk space", false, "%s", err)
+	} else {
+		r.add("disk space", free >= doctorMinFreeSpace, "%d MB available", free>>20)
+	}
+
+	for _, dir := range []string{c.DestDir, c.profilesDir()} {
+		if err := checkWritable(dir); err != nil {
+			r.add("write permission", false, "%s: %s", dir, err)
+		} else {
+			r.add("write permission", true, "%s", dir)
+		}
+	}
+
+	return r
+}
+
+// commandVersion returns the first line output by name args
+func commandVersion(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0]), nil
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// existingParent returns dir or its nearest existing parent
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// freeSpace returns the space available to the user on the file system of dir
+func freeSpace(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+
+	if err := syscall.Statfs(existingParent(dir), &st); err != nil {
+		return 0, err
+	}
+
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
+// checkWritable checks a file can be created in dir (or its nearest existing
+// parent if it doesn't exist yet)
+func checkWritable(dir string) error {
+	f, err := ioutil.TempFile(existingParent(dir), ".dojoBuilderDoctor")
+	if err != nil {
+		return err
+	}
+
+	f.Close()
+
+	return os.Remove(f.Name())
+}
@@ -1,21 +1,61 @@
 package dojoBuilder
 
 import (
-	"errors"
-	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 )
 
 type Config struct {
-	BuildMode         bool   // Use dojo build if true
-	SrcDir            string // Absolute path of the src js dir
-	DestDir           string // Absolute path where the output files will be placed
-	Bin               string // Name of the bin used to build dojo (optional) [node, node-debug, java]
-	DojoConfigRelPath string // Path (relative to SrcDir) of the file containing the dojoConfig JSON
-	BuildConfigs      map[string]BuildConfig
+	SchemaVersion     int     `json:"schemaVersion"`               // Version of the config file format, see ConfigSchemaVersion
+	BuildMode         bool    `json:"buildMode,omitempty"`         // Use dojo build if true
+	SrcDir            string  `json:"srcDir"`                      // Absolute path of the src js dir
+	DojoDir           string  `json:"dojoDir,omitempty"`           // Absolute path of the dir of the dojo, dijit, dojox and util checkouts (optional, default SrcDir)
+	ToolkitChecksums  string  `json:"toolkitChecksums,omitempty"`  // Path of the checksums of the dojo checkout, written by WriteToolkitChecksums, verified before building (optional)
+	DestDir           string  `json:"destDir"`                     // Absolute path where the output files will be placed
+	ReadOnlySrc       bool    `json:"readOnlySrc,omitempty"`       // Never write into SrcDir, the profiles are generated in DestDir
+	Bin               string  `json:"bin,omitempty"`               // Name of the bin used to build dojo (optional) [node, node-debug, java]
+	EsbuildBin        string  `json:"esbuildBin,omitempty"`        // Path of the esbuild executable used by FastBuildMode (optional, default "esbuild")
+	NodeBin           string  `json:"nodeBin,omitempty"`           // Path of the node executable running smoke tests (optional, default "node")
+	ChromeBin         string  `json:"chromeBin,omitempty"`         // Path of the headless Chrome used to verify builds (optional)
+	DojoConfigRelPath string  `json:"dojoConfigRelPath,omitempty"` // Path (relative to SrcDir) of the file containing the dojoConfig JSON
+	Snapshot          string  `json:"snapshot,omitempty"`          // Build from a snapshot of SrcDir (optional) [SnapshotLink, SnapshotCopy]
+	GitRef            string  `json:"gitRef,omitempty"`            // Build from this git revision of the repository of SrcDir instead of the working tree (optional)
+	SpaceFactor       float64 `json:"spaceFactor,omitempty"`       // Space needed by a build as a factor of SrcDir size (optional, default DefaultSpaceFactor, negative to skip the check)
+	JUnitReport       string  `json:"junitReport,omitempty"`       // Path of the JUnit XML report of the builds (optional)
+	SizeBaseline      string  `json:"sizeBaseline,omitempty"`      // Path of the file storing the layer sizes of the last successful builds (optional)
+	CacheDir          string  `json:"cacheDir,omitempty"`          // Directory of the build cache, shared by the configs of a Workspace: the releases of the dojo builder are reused for identical profiles and sources (optional)
+	History           string  `json:"history,omitempty"`           // Path of the file every build result is appended to (optional)
+	Proxy             string  `json:"proxy,omitempty"`             // Url of the proxy of the deploy commands, webhooks and health checks (optional, default the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables)
+	AuditLog          string  `json:"auditLog,omitempty"`          // Path of the append-only file every build, deployment and promotion is recorded in, see AuditEvent (optional)
+	Nice              int     `json:"nice,omitempty"`              // Niceness (1-19) the build processes run with (optional)
+	IONiceClass       int     `json:"ioniceClass,omitempty"`       // ionice scheduling class (1 realtime, 2 best-effort, 3 idle) the build processes run with (optional)
+	MaxHeapMB         int     `json:"maxHeapMB,omitempty"`         // Heap (MB) of the java or node optimizer (optional, default the build.sh one)
+	OOMRetries        int     `json:"oomRetries,omitempty"`        // Times a build running out of memory is retried, doubling the heap (optional)
+	SyncRelease       bool    `json:"syncRelease,omitempty"`       // Flush the files and directories copied into DestDir to the disk before the build ends
+	LockTimeout       int     `json:"lockTimeout,omitempty"`       // Seconds waited for another build to release DestDir (optional, default 0 waits forever, negative fails right away)
+
+	BuildExcludes   []string `json:"buildExcludes,omitempty"`   // Names of the registered exclude funcs used instead of the build one (optional)
+	InstallExcludes []string `json:"installExcludes,omitempty"` // Names of the registered exclude funcs used instead of the install one (optional)
+	ArchiveExcludes []string `json:"archiveExcludes,omitempty"` // Names of the registered exclude funcs used instead of the archive one (optional)
+
+	Schedules []Schedule `json:"schedules,omitempty"` // Builds run periodically by a Watcher (optional)
+
+	BuildConfigs map[string]BuildConfig `json:"buildConfigs"`
+
+	Output    io.Writer      `json:"-"` // Receives the output of the build processes (optional, default os.Stdout and os.Stderr)
+	AuditSink AuditSink      `json:"-"` // Also receives the events of the AuditLog (optional)
+	VCS       VCSProvider    `json:"-"` // Provides the revision of SrcDir stamped in the BuildInfo, the history and the AuditLog (optional, default GitVCS)
+	Secrets   SecretProvider `json:"-"` // Provides the credentials of the deploy targets and health checks, see Config.Secret (optional, default EnvSecrets(""))
+
+	result   *BuildResult    // Result of the running build
+	cancel   <-chan struct{} // Closed to cancel the running build
+	buildID  string          // Id of the running Build, namespacing its intermediate paths
+	revision VCSInfo         // Revision of the sources of the running Build
+	locked   bool            // DestDir is locked by the running Run or Build
+	profile  []byte          // Profile built instead of the generated ones, see BuildWithProfile
 }
 
 type HookFunc func() error
@@ -31,22 +71,21 @@ var beforeHook, afterHook HookFunc
 
 func Run(c *Config, names []string, reset bool) (err error) {
 	if c.DestDir == "" {
-		return errors.New("No DestDir defined in config")
+		return ErrNoDestDir
 	}
 
-	if _, err = os.Stat(c.DestDir); os.IsNotExist(err) {
-		if err = os.MkdirAll(c.DestDir, 0754); err != nil {
-			return
-		}
+	unlock, err := c.lockDestDir()
+	if err != nil {
+		return
 	}
+	defer unlock()
+
+	lc := *c
+	lc.locked = true
+	c = &lc
 
 	if reset {
-		filepath.Walk(c.DestDir, func(path string, f os.FileInfo, err error) (_err error) {
-			if path != c.DestDir {
-				_err = os.RemoveAll(path)
-			}
-			return
-		})
+		c.resetDestDir()
 	}
 
 	if beforeHook != nil {
@@ -74,14 +113,39 @@ func Run(c *Config, names []string, reset bool) (err error) {
 	return
 }
 
+// resetDestDir empties DestDir, which must be locked
+func (c *Config) resetDestDir() {
+	filepath.Walk(c.DestDir, func(path string, f os.FileInfo, err error) (_err error) {
+		// Removing the lock file would let another build lock a new one
+		if path != c.DestDir && filepath.Base(path) != LockFileName {
+			_err = os.RemoveAll(path)
+		}
+		return
+	})
+}
+
+// GetDojoConfig returns the dojoConfig JSON of the release at the top level
+// of DestDir, see Config.DojoConfig for the VersionedLayout
 func GetDojoConfig(c *Config) (template.JS, error) {
-	dojoConfigFilePath := fmt.Sprintf("%s/%s", c.DestDir, c.DojoConfigRelPath)
+	return readDojoConfig(filepath.Join(c.DestDir, filepath.FromSlash(c.DojoConfigRelPath)))
+}
+
+// DojoConfig returns the dojoConfig JSON of the release of the build config
+// name, in its ReleaseRoot
+func (c *Config) DojoConfig(name string) (template.JS, error) {
+	root, err := c.ReleaseRoot(name)
+	if err != nil {
+		return "", err
+	}
 
-	b, err := ioutil.ReadFile(dojoConfigFilePath)
+	return readDojoConfig(filepath.Join(c.DestDir, root, filepath.FromSlash(c.DojoConfigRelPath)))
+}
 
+func readDojoConfig(path string) (template.JS, error) {
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		return "", err
 	}
 
-	return template.JS(b), err
+	return template.JS(b), nil
 }
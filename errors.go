@@ -0,0 +1,250 @@
+package dojoBuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrConfigNotFound is returned for an unknown build config name
+	ErrConfigNotFound = errors.New("No build config found")
+
+	// ErrLayerNotFound is returned for a layer missing from its build config
+	ErrLayerNotFound = errors.New("No layer found")
+
+	// ErrNoDestDir is returned when the config has no DestDir
+	ErrNoDestDir = errors.New("No DestDir defined in config")
+
+	// ErrProfileGeneration is returned when the build profile of a build
+	// config cannot be generated
+	ErrProfileGeneration = errors.New("Cannot generate the build profile")
+
+	// ErrBuildCanceled is returned by a build whose cancel channel was closed
+	ErrBuildCanceled = errors.New("Build canceled")
+
+	// ErrOutOfMemory is returned when the optimizer runs out of memory
+	ErrOutOfMemory = errors.New("Build ran out of memory")
+
+	// ErrToolkitModified is returned when the dojo checkout differs from
+	// the checksums of Config.ToolkitChecksums
+	ErrToolkitModified = errors.New("The dojo checkout differs from its recorded checksums")
+)
+
+// ErrDestDirLocked is returned when DestDir is locked by another build for
+// longer than Config.LockTimeout
+type ErrDestDirLocked struct {
+	DestDir string
+	PID     int // Process holding the lock, 0 if unknown
+}
+
+func (e *ErrDestDirLocked) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("%s is locked by another build", e.DestDir)
+	}
+
+	return fmt.Sprintf("%s is locked by the build of process %d", e.DestDir, e.PID)
+}
+
+// ErrDeployTimeout is returned when a deployment lasts longer than the
+// Timeout of its DeployTarget
+type ErrDeployTimeout struct {
+	Target  string
+	Timeout time.Duration
+	Err     error // Error of the stopped deployment, e.g. an ErrDeployPartial
+}
+
+func (e *ErrDeployTimeout) Error() string {
+	msg := fmt.Sprintf("Deployment to %s stopped after %s", e.Target, e.Timeout)
+	if e.Err != nil && e.Err != context.DeadlineExceeded {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *ErrDeployTimeout) Unwrap() error { return e.Err }
+
+// ErrDeployPartial is returned by the deployers uploading the files one by
+// one, e.g. UploadDeployer, when some of them are not uploaded
+type ErrDeployPartial struct {
+	Total   int              // Number of files of the deployment
+	Failed  map[string]error // Path, relative to DestDir, of the files whose upload failed => error
+	Skipped []string         // Paths of the files not uploaded, the deployment being stopped
+}
+
+// Files returns the paths of the files not uploaded, sorted
+func (e *ErrDeployPartial) Files() []string {
+	files := append([]string(nil), e.Skipped...)
+	for p := range e.Failed {
+		files = append(files, p)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+func (e *ErrDeployPartial) Error() string {
+	msg := fmt.Sprintf("%d of %d files not uploaded", len(e.Failed)+len(e.Skipped), e.Total)
+
+	// The first failure explains the others most of the time
+	for _, p := range e.Files() {
+		if err, ok := e.Failed[p]; ok {
+			return msg + ", " + p + ": " + err.Error()
+		}
+	}
+
+	return msg
+}
+
+// ErrBuildFailed is returned when the dojo build script fails
+type ErrBuildFailed struct {
+	Command    string         // Command line of the build script
+	ExitCode   int            // Exit status of the build script, -1 if killed or unknown
+	Signal     syscall.Signal // Signal which killed the build script, 0 if none
+	Summary    string         // Errors reported by the dojo builder, if any
+	OutputTail string         // Last lines output by the build script
+	Err        error          // Error of the command execution
+}
+
+// newBuildFailed returns the ErrBuildFailed of cmd which failed with err
+func newBuildFailed(cmd *exec.Cmd, err error, summary, tail string) *ErrBuildFailed {
+	e := &ErrBuildFailed{
+		Command:    commandLine(cmd.Args),
+		ExitCode:   -1,
+		Summary:    summary,
+		OutputTail: tail,
+		Err:        err,
+	}
+
+	if ee, ok := err.(*exec.ExitError); ok {
+		e.ExitCode = ee.ExitCode()
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			e.Signal = ws.Signal()
+		}
+	}
+
+	return e
+}
+
+func (e *ErrBuildFailed) Error() string {
+	msg := "Build command failed (" + e.Command + ")"
+	if e.Signal != 0 {
+		msg += fmt.Sprintf(", killed by signal %d (%s)", int(e.Signal), e.Signal)
+	} else if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" with status %d", e.ExitCode)
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	if e.Summary != "" {
+		msg += ":\n" + e.Summary
+	} else if e.OutputTail != "" {
+		msg += ":\n" + e.OutputTail
+	}
+
+	return msg
+}
+
+func (e *ErrBuildFailed) Unwrap() error { return e.Err }
+
+// commandLine returns args as a shell command line
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$") {
+			a = "'" + strings.Replace(a, "'", `'\''`, -1) + "'"
+		}
+		quoted[i] = a
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func configNotFound(name string) error {
+	return fmt.Errorf("%w with name '%s'", ErrConfigNotFound, name)
+}
+
+func layerNotFound(name, mid string) error {
+	return fmt.Errorf("%w with name '%s' in build config '%s'", ErrLayerNotFound, mid, name)
+}
+
+// profileError wraps an error of the profile generation
+type profileError struct {
+	err error
+}
+
+func (e profileError) Error() string { return ErrProfileGeneration.Error() + ": " + e.err.Error() }
+
+func (e profileError) Unwrap() error { return e.err }
+
+func (e profileError) Is(target error) bool { return target == ErrProfileGeneration }
+
+// buildOutputTailLines is the number of output and error lines kept in
+// ErrBuildFailed
+const buildOutputTailLines = 20
+
+// outputTailLineMax is the max length of a line kept by outputTail
+const outputTailLineMax = 4096
+
+// outputTail keeps the last lines added or written to it, the output and the
+// error output of a command writing concurrently
+type outputTail struct {
+	mu      sync.Mutex
+	lines   []string
+	partial string // Last line written, not yet terminated
+	max     int
+}
+
+func (t *outputTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.push(line)
+}
+
+func (t *outputTail) push(line string) {
+	if len(line) > outputTailLineMax {
+		line = line[:outputTailLineMax]
+	}
+
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := strings.Split(t.partial+string(p), "\n")
+	for _, l := range lines[:len(lines)-1] {
+		t.push(strings.TrimRight(l, "\r"))
+	}
+
+	t.partial = lines[len(lines)-1]
+	if len(t.partial) > outputTailLineMax {
+		t.partial = t.partial[:outputTailLineMax]
+	}
+
+	return len(p), nil
+}
+
+func (t *outputTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := t.lines
+	if t.partial != "" {
+		lines = append(lines[:len(lines):len(lines)], t.partial)
+	}
+
+	return strings.Join(lines, "\n")
+}
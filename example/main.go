@@ -30,7 +30,10 @@ const pageTemplate = `<!doctype html>
 </body>
 </html>`
 
-var builderConfig *dojoBuilder.Config
+var (
+	builderConfig *dojoBuilder.Config
+	archivePath   string
+)
 
 func getDojoConfig() template.JS {
 	dc, err := dojoBuilder.GetDojoConfig(builderConfig)
@@ -59,6 +62,7 @@ func init() {
 	}
 
 	buildMode := flag.Bool("buildMode", false, "Use build mode of dojoBuilder")
+	flag.StringVar(&archivePath, "archive", "", "Write the built files into this archive (.tar.gz or .zip) and exit")
 
 	flag.Parse()
 
@@ -122,6 +126,16 @@ func main() {
 	if err := dojoBuilder.Run(builderConfig, nil, true); err != nil {
 		log.Fatal(err)
 	}
+
+	if archivePath != "" {
+		dojoBuilder.SetArchiveExcludeFunc(dojoBuilder.DefaultArchiveExcludeFunc)
+		if err := builderConfig.Archive(archivePath, ""); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Archive written to %s\n", archivePath)
+		return
+	}
+
 	http.HandleFunc("/", handler)
 	http.Handle("/pkg/", http.StripPrefix("/pkg/", http.FileServer(http.Dir(builderConfig.DestDir))))
 	fmt.Printf("\nHTTP server is running.\nPlease visit http://127.0.0.1:8080 to see the result.\n")
@@ -0,0 +1,125 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	excludeFuncsMu sync.RWMutex
+	excludeFuncs   = map[string]ExcludeFunc{
+		"default-build":   DefaultBuildExcludeFunc,
+		"default-install": DefaultInstallExcludeFunc,
+		"default-archive": DefaultArchiveExcludeFunc,
+	}
+)
+
+// RegisterExcludeFunc registers f under name, so file-based configs can
+// reference it in their buildExcludes, installExcludes or archiveExcludes.
+// The default exclude funcs are registered as "default-build",
+// "default-install" and "default-archive".
+func RegisterExcludeFunc(name string, f ExcludeFunc) {
+	excludeFuncsMu.Lock()
+	defer excludeFuncsMu.Unlock()
+
+	excludeFuncs[name] = f
+}
+
+// checkExcludeFuncs returns an error if one of names is not registered
+func checkExcludeFuncs(names []string) error {
+	excludeFuncsMu.RLock()
+	defer excludeFuncsMu.RUnlock()
+
+	for _, n := range names {
+		if _, ok := excludeFuncs[n]; !ok {
+			return fmt.Errorf("No exclude func registered as '%s'", n)
+		}
+	}
+
+	return nil
+}
+
+// ExcludeStats counts the paths skipped by exclude funcs
+type ExcludeStats struct {
+	Files int
+	Dirs  int
+	Bytes int64          // Size of the skipped files, directories content included
+	Rules map[string]int // Exclude func name ("default" for the one set by Set*ExcludeFunc) => skipped paths
+}
+
+func (s *ExcludeStats) add(rule, path string, f os.FileInfo) {
+	if s.Rules == nil {
+		s.Rules = make(map[string]int)
+	}
+	s.Rules[rule]++
+
+	if f.IsDir() {
+		s.Dirs++
+		size, _ := dirSize(path)
+		s.Bytes += size
+	} else {
+		s.Files++
+		s.Bytes += f.Size()
+	}
+}
+
+// Print writes the stats to w, the rules skipping the most paths first
+func (s *ExcludeStats) Print(w io.Writer) {
+	fmt.Fprintf(w, "Excluded %d files and %d directories (%d bytes)\n", s.Files, s.Dirs, s.Bytes)
+
+	rules := make([]string, 0, len(s.Rules))
+	for r := range s.Rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if s.Rules[rules[i]] != s.Rules[rules[j]] {
+			return s.Rules[rules[i]] > s.Rules[rules[j]]
+		}
+		return rules[i] < rules[j]
+	})
+
+	for _, r := range rules {
+		fmt.Fprintf(w, "  %s: %d\n", r, s.Rules[r])
+	}
+}
+
+// namedExcludeFunc returns an ExcludeFunc excluding the paths excluded by any
+// of the funcs registered under names, or by def if names is empty. The
+// skipped paths are counted into stats unless it is nil.
+func namedExcludeFunc(names []string, def ExcludeFunc, stats *ExcludeStats) (ExcludeFunc, error) {
+	funcs := map[string]ExcludeFunc{"default": def}
+	if len(names) == 0 {
+		names = []string{"default"}
+	} else {
+		excludeFuncsMu.RLock()
+		defer excludeFuncsMu.RUnlock()
+
+		funcs = make(map[string]ExcludeFunc, len(names))
+		for _, n := range names {
+			f, ok := excludeFuncs[n]
+			if !ok {
+				return nil, fmt.Errorf("No exclude func registered as '%s'", n)
+			}
+			funcs[n] = f
+		}
+	}
+
+	return func(path string, f os.FileInfo) (bool, error) {
+		for _, n := range names {
+			skip, err := funcs[n](path, f)
+			if err != nil {
+				return false, err
+			} else if skip {
+				if stats != nil {
+					stats.add(n, path, f)
+				}
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}, nil
+}
@@ -0,0 +1,65 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Explain returns the profile of the build config name as the dojo builder
+// receives it, defaults applied and paths resolved, but indented. It starts
+// with a comment giving the directories and the settings of the build config
+// which are not part of the profile.
+func (c *Config) Explain(name string) (string, error) {
+	rc, _ := c.withLayerConditions(name)
+	bc, j, _, err := rc.resolveBuildConfig(name)
+	c.removeProfile(name, "")
+	if err != nil {
+		return "", err
+	}
+
+	var indented bytes.Buffer
+	if err = json.Indent(&indented, j, "", "\t"); err != nil {
+		return "", err
+	}
+
+	t, err := parseProfileTemplate(bc)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := newProfileData(name, bc, indented.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	mode := bc.Mode
+	if mode == "" {
+		mode = ReleaseBuildMode
+	}
+
+	layout := bc.Layout
+	if layout == "" {
+		layout = "standard"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Build config: %s\n", name)
+	fmt.Fprintf(&b, "// Sources: %s\n", c.SrcDir)
+	fmt.Fprintf(&b, "// Toolkit: %s\n", c.ToolkitDir())
+	fmt.Fprintf(&b, "// Destination: %s\n", c.DestDir)
+	fmt.Fprintf(&b, "// Mode: %s, layout: %s\n", mode, layout)
+	if bc.Transpiler != nil {
+		fmt.Fprintf(&b, "// Transpiled packages: %s\n", strings.Join(bc.Transpiler.Packages, ", "))
+	}
+	if len(bc.Themes) > 0 {
+		fmt.Fprintf(&b, "// Themes: %d\n", len(bc.Themes))
+	}
+
+	if err = t.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
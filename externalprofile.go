@@ -0,0 +1,82 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// ExternalProfileName is the name of the build run by BuildWithProfile
+const ExternalProfileName = "external"
+
+// BuildWithProfile builds the profile document read from r, generated
+// elsewhere, instead of a generated one. Its release is output into DestDir
+// like the one of a build config: copied with the build exclude funcs and
+// post processed. Its relative paths are resolved from SrcDir/profiles.
+//
+// The layers of the profile are reported when its object is JSON, e.g.
+// "var profile = {...};" with quoted keys. The build settings only set from
+// Go (Layout, Themes...) are the defaults.
+func (c *Config) BuildWithProfile(r io.Reader) (*BuildResult, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, errors.New("The profile is empty")
+	}
+
+	bc, err := parseProfile(b)
+	if err != nil {
+		fmt.Fprintf(c.stdout(), "Warning: cannot parse the profile (%s), its layers are not reported\n", err)
+	}
+
+	ec := *c
+	ec.BuildConfigs = map[string]BuildConfig{ExternalProfileName: bc}
+	ec.profile = b
+
+	results, err := ec.Build([]string{ExternalProfileName})
+	if len(results) == 0 {
+		return nil, err
+	}
+
+	return results[0], err
+}
+
+// parseProfile returns the build config of the JSON object of the profile
+// document b
+func parseProfile(b []byte) (bc BuildConfig, err error) {
+	start, end := bytes.IndexByte(b, '{'), bytes.LastIndexByte(b, '}')
+	if start < 0 || end < start {
+		return bc, errors.New("no profile object found")
+	}
+
+	err = json.Unmarshal(b[start:end+1], &bc)
+
+	return
+}
+
+// writeExternalProfile writes the profile of BuildWithProfile into the
+// profiles directory
+func (c *Config) writeExternalProfile(name string) (bc BuildConfig, profileFullPath string, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return bc, "", configNotFound(name)
+	}
+
+	bc.ReleaseDir = c.releaseDir(name)
+
+	if err = os.MkdirAll(c.profilesDir(), 0754); err != nil {
+		return
+	}
+
+	profileFullPath = c.workPath(c.profilesDir(), name, "") + ".profile.js"
+	err = ioutil.WriteFile(profileFullPath, c.profile, 0664)
+
+	return bc, profileFullPath, err
+}
@@ -0,0 +1,173 @@
+package dojoBuilder
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractRelease installs into destDir the release archive made by
+// Config.Archive, after checking its files match the archive manifest.
+// The previous content of destDir is replaced.
+func ExtractRelease(archive, destDir string) (err error) {
+	format, err := ArchiveFormat(archive)
+	if err != nil {
+		return
+	}
+
+	destDir = filepath.Clean(destDir)
+	tmpDir := destDir + ".dojoBuilderExtract"
+
+	os.RemoveAll(tmpDir)
+	if err = os.MkdirAll(tmpDir, 0754); err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if format == ArchiveZip {
+		err = extractZip(archive, tmpDir)
+	} else {
+		err = extractTarGz(archive, tmpDir)
+	}
+
+	if err != nil {
+		return
+	}
+
+	m, err := ReadManifest(filepath.Join(tmpDir, ManifestFileName))
+	if err != nil {
+		return fmt.Errorf("Cannot read the manifest of %s: %s", archive, err)
+	}
+
+	if err = m.Verify(tmpDir); err != nil {
+		return
+	}
+
+	if err = os.RemoveAll(destDir); err != nil {
+		return
+	}
+
+	return os.Rename(tmpDir, destDir)
+}
+
+// extractPath returns the path where the archive entry name has to be
+// extracted into dir, refusing entries escaping dir.
+func extractPath(dir, name string) (string, error) {
+	p := filepath.Join(dir, filepath.FromSlash(name))
+
+	if p != dir && !strings.HasPrefix(p, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("Invalid archive entry '%s'", name)
+	}
+
+	return p, nil
+}
+
+func extractTarGz(archive, dir string) (err error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		p, err := extractPath(dir, h.Name)
+		if err != nil {
+			return err
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(p, 0754)
+		case tar.TypeReg:
+			err = writeExtractedFile(p, tr, os.FileMode(h.Mode).Perm())
+		default:
+			err = fmt.Errorf("Unsupported archive entry type for '%s'", h.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(archive, dir string) (err error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		p, err := extractPath(dir, zf.Name)
+		if err != nil {
+			return err
+		}
+
+		if zf.FileInfo().IsDir() {
+			if err = os.MkdirAll(p, 0754); err != nil {
+				return err
+			}
+			continue
+		} else if !zf.FileInfo().Mode().IsRegular() {
+			return fmt.Errorf("Unsupported archive entry type for '%s'", zf.Name)
+		}
+
+		r, err := zf.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeExtractedFile(p, r, zf.Mode().Perm())
+		r.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return
+}
+
+func writeExtractedFile(p string, r io.Reader, perm os.FileMode) (err error) {
+	if err = os.MkdirAll(filepath.Dir(p), 0754); err != nil {
+		return
+	}
+
+	if perm == 0 {
+		perm = 0664
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	_, err = io.Copy(f, r)
+
+	return
+}
@@ -0,0 +1,216 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
+)
+
+const defaultEsbuildBin = "esbuild"
+
+// esbuildArgsMax is the max length of the file arguments of an esbuild
+// command, far below the ARG_MAX of the supported systems
+const esbuildArgsMax = 64 << 10
+
+// executeFastBuild fills the release directory using esbuild instead of the
+// dojo builder. Every module of the packages is minified on its own and keeps
+// its define() call, so that the modules out of the layers still load, then
+// every layer is bundled like the dojo builder does: its modules in the
+// require cache, followed by the layer module. It is meant for development
+// only: no optimization crosses the module boundaries.
+func (c *Config) executeFastBuild(name string) (err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return configNotFound(name)
+	}
+
+	releaseDir := c.releaseDir(name)
+
+	for _, p := range c.sourcePackages(bc.Packages) {
+		src := p.Dir(c.SrcDir)
+		dest := filepath.Join(releaseDir, p.ReleaseLocation())
+
+		var jsFiles []string
+
+		err = filepath.Walk(src, func(path string, f os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			target, err := destPath(src, dest, path)
+			if err != nil {
+				return err
+			}
+
+			if f.IsDir() {
+				return os.MkdirAll(target, 0754)
+			} else if filepath.Ext(path) == ".js" {
+				jsFiles = append(jsFiles, path)
+				return nil
+			}
+
+			return copyutil.CopyFile(path, target)
+		})
+
+		if err != nil {
+			return
+		}
+
+		for len(jsFiles) > 0 {
+			n, size := 0, 0
+			for ; n < len(jsFiles) && (n == 0 || size+len(jsFiles[n]) < esbuildArgsMax); n++ {
+				size += len(jsFiles[n]) + 1
+			}
+
+			args := append([]string{"--minify", "--outbase=" + src, "--outdir=" + dest}, jsFiles[:n]...)
+			if err = c.runEsbuild(src, args, nil, nil); err != nil {
+				return
+			}
+
+			jsFiles = jsFiles[n:]
+		}
+	}
+
+	srcRes := NewResolver(c.SrcDir, c.sourcePackages(bc.Packages), bc.Map).WithAliases(bc.Aliases)
+	releaseRes := NewResolver(releaseDir, releasePackages(bc.Packages), nil)
+
+	for mid, l := range bc.Layers {
+		p, err := releaseRes.Path(mid)
+		if err != nil {
+			return err
+		}
+
+		var out bytes.Buffer
+		if err = c.runEsbuild(mid, []string{"--minify", "--loader=js"}, bytes.NewReader(fastLayerSource(srcRes, mid, l)), &out); err != nil {
+			return err
+		}
+
+		if err = os.MkdirAll(filepath.Dir(p), 0754); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(p, out.Bytes(), 0664); err != nil {
+			return err
+		}
+	}
+
+	return
+}
+
+// fastLayerModules returns the modules of the layer mid, res resolving the
+// source modules: its includes and the modules they depend on, but the
+// layer itself and the modules its excludes depend on
+func fastLayerModules(res *Resolver, mid string, l Layer) []string {
+	walk := func(roots []string) map[string][]byte {
+		sources := make(map[string][]byte)
+		seen := make(map[string]bool)
+
+		for queue := roots; len(queue) > 0; {
+			m := queue[0]
+			queue = queue[1:]
+
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+
+			// require, exports, module and the modules the build generates
+			p, err := res.Path(m)
+			if err != nil {
+				continue
+			}
+			b, err := ioutil.ReadFile(p)
+			if err != nil {
+				continue
+			}
+
+			sources[m] = b
+			queue = append(queue, moduleDependencies(m, b)...)
+		}
+
+		return sources
+	}
+
+	excluded := walk(l.Exclude)
+
+	var modules []string
+	for m := range walk(append([]string{mid}, l.Include...)) {
+		if _, ok := excluded[m]; !ok && m != mid {
+			modules = append(modules, m)
+		}
+	}
+	sort.Strings(modules)
+
+	return modules
+}
+
+// fastLayerSource returns the source of the layer mid bundled by the fast
+// build, in the format of the dojo builder: the require cache of its modules
+// and the layer module, or a placeholder when it has no source
+func fastLayerSource(res *Resolver, mid string, l Layer) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("require({cache:{\n")
+	for _, m := range fastLayerModules(res, mid, l) {
+		p, _ := res.Path(m)
+		src, err := ioutil.ReadFile(p)
+		if err != nil {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:function(){\n", strconv.Quote(m))
+		b.Write(src)
+		b.WriteString("\n},\n")
+	}
+	b.WriteString("}});\n")
+
+	if p, err := res.Path(mid); err == nil {
+		if src, err := ioutil.ReadFile(p); err == nil {
+			b.Write(src)
+			return b.Bytes()
+		}
+	}
+
+	b.WriteString("define([], 1);\n")
+
+	return b.Bytes()
+}
+
+// runEsbuild runs esbuild with args, reading stdin and writing stdout when
+// not nil. what names the files processed in the errors.
+func (c *Config) runEsbuild(what string, args []string, stdin io.Reader, stdout io.Writer) (err error) {
+	bin := c.EsbuildBin
+	if bin == "" {
+		bin = defaultEsbuildBin
+	}
+
+	cmd := c.command(bin, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	if stdout == nil {
+		cmd.Stdout = c.stdout()
+	}
+
+	if err = cmd.Start(); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	c.killOnCancel(cmd, done)
+	err = cmd.Wait()
+	close(done)
+
+	if c.canceled() {
+		return ErrBuildCanceled
+	} else if err != nil {
+		return fmt.Errorf("esbuild failed for %s", what)
+	}
+
+	return
+}
@@ -0,0 +1,114 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CommitFileName is the name of the file written in DestDir containing the
+// git commit the build was made from, when built from a GitRef.
+const CommitFileName = "dojoBuilder.commit"
+
+// git runs the git command args in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkoutGitRef checks out the GitRef of the repository of SrcDir, with its
+// submodules, into a directory of DestDir. It returns a copy of c using the
+// checkout as SrcDir (and DojoDir when in the repository), the checked out
+// commit and the checkout directory. The commit is written into DestDir.
+func (c *Config) checkoutGitRef() (gc *Config, commit, dir string, err error) {
+	top, err := git(c.SrcDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return
+	}
+
+	// rev-parse returns a clean path, SrcDir may be a symlink
+	if top, err = filepath.EvalSymlinks(top); err != nil {
+		return
+	}
+
+	if commit, err = git(c.SrcDir, "rev-parse", "--verify", "--quiet", c.GitRef+"^{commit}"); err != nil {
+		return nil, "", "", fmt.Errorf("Unknown git revision '%s'", c.GitRef)
+	}
+
+	dir = c.workPath(c.DestDir, "dojoBuilderGIT", "")
+	os.RemoveAll(dir)
+
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if _, err = git(c.DestDir, "clone", "--quiet", "--shared", "--no-checkout", top, dir); err != nil {
+		return
+	}
+
+	if _, err = git(dir, "checkout", "--quiet", "--detach", commit); err != nil {
+		return
+	}
+
+	if _, serr := os.Stat(filepath.Join(dir, ".gitmodules")); serr == nil {
+		if _, err = git(dir, "submodule", "update", "--quiet", "--init", "--recursive"); err != nil {
+			return
+		}
+	}
+
+	cc := *c
+
+	for _, p := range []*string{&cc.SrcDir, &cc.DojoDir} {
+		if *p == "" {
+			continue
+		}
+
+		abs, rerr := filepath.EvalSymlinks(*p)
+		if rerr != nil {
+			return nil, "", "", rerr
+		}
+
+		// A DojoDir outside the repository is used as is
+		if d, derr := destPath(top, dir, abs); derr == nil {
+			*p = d
+		} else if p == &cc.SrcDir {
+			return nil, "", "", derr
+		}
+	}
+
+	fmt.Fprintf(c.stdout(), "Building from commit %s (%s)\n", commit, c.GitRef)
+
+	if err = ioutil.WriteFile(filepath.Join(c.DestDir, CommitFileName), []byte(commit+"\n"), 0664); err != nil {
+		return
+	}
+
+	return &cc, commit, dir, nil
+}
+
+// builtCommit returns the commit DestDir was built from, "" if unknown
+func (c *Config) builtCommit() string {
+	b, err := ioutil.ReadFile(filepath.Join(c.DestDir, CommitFileName))
+	if err != nil {
+		if info, ierr := ReadBuildInfo(c.DestDir); ierr == nil {
+			return info.Commit
+		}
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
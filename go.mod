@@ -0,0 +1,3 @@
+module github.com/tbaud0n/dojoBuilder
+
+go 1.16
@@ -0,0 +1,94 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+const defaultHealthCheckTimeout = 30 * time.Second
+
+// HealthCheck verifies a deployed release is actually served, by fetching
+// one of its files through the real web server
+type HealthCheck struct {
+	URL           string            // Url fetched, e.g. the one of the boot layer
+	File          string            // Release file, relative to DestDir, the response body must be identical to (optional)
+	Status        int               // Expected status code (optional, default 200)
+	Timeout       time.Duration     // Timeout of the request (optional, default 30s)
+	HeaderSecrets map[string]string // Request header => name of the secret it is set to, e.g. "Authorization" (optional)
+}
+
+// Check fetches the url of h and compares the response with the release in
+// destDir. The HeaderSecrets are read from the environment, see EnvSecrets.
+func (h HealthCheck) Check(destDir string) error {
+	return h.check(&Config{DestDir: destDir})
+}
+
+// check fetches the url of h, with the secrets of c, and compares the
+// response with the release in the DestDir of c
+func (h HealthCheck) check(c *Config) error {
+	destDir := c.DestDir
+
+	headers, err := c.secretValues(h.HeaderSecrets)
+	if err != nil {
+		return err
+	}
+
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	status := h.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	client, err := c.httpClient(timeout)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, h.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return fmt.Errorf("%s returned status %d instead of %d", h.URL, resp.StatusCode, status)
+	}
+
+	if h.File == "" {
+		return nil
+	}
+
+	want, err := ioutil.ReadFile(filepath.Join(destDir, filepath.FromSlash(h.File)))
+	if err != nil {
+		return err
+	}
+
+	got := sha256.New()
+	if _, err = io.Copy(got, resp.Body); err != nil {
+		return err
+	}
+
+	if sum := sha256.Sum256(want); !bytes.Equal(got.Sum(nil), sum[:]) {
+		return fmt.Errorf("%s does not serve the release %s", h.URL, h.File)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package dojoBuilder
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// HistoryRecord is the record of a build in the build history
+type HistoryRecord struct {
+	Time       time.Time        `json:"time"`
+	Name       string           `json:"name"` // Build config name
+	BuildID    string           `json:"buildId,omitempty"`
+	Commit     string           `json:"commit,omitempty"` // Commit built, if known
+	Duration   time.Duration    `json:"duration"`
+	Error      string           `json:"error,omitempty"`
+	Warnings   int              `json:"warnings"`
+	Errors     int              `json:"errors"`
+	PeakMemory int64            `json:"peakMemory,omitempty"`
+	Layers     map[string]int64 `json:"layers,omitempty"` // Layer module id => size
+}
+
+// History is a list of build records, oldest first
+type History []HistoryRecord
+
+// appendHistory appends the records of results to the history file at path,
+// one JSON record per line
+func appendHistory(path string, results []*BuildResult) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	enc := json.NewEncoder(f)
+	now := time.Now()
+
+	for _, r := range results {
+		hr := HistoryRecord{
+			Time:       now,
+			Name:       r.Name,
+			BuildID:    r.BuildID,
+			Commit:     r.Commit,
+			Duration:   r.Duration,
+			Warnings:   r.Warnings,
+			Errors:     r.Errors,
+			PeakMemory: r.PeakMemory,
+		}
+
+		if r.Err != nil {
+			hr.Error = r.Err.Error()
+		}
+
+		for _, l := range r.Layers {
+			if l.Err == nil {
+				if hr.Layers == nil {
+					hr.Layers = make(map[string]int64)
+				}
+				hr.Layers[l.Name] = l.Size
+			}
+		}
+
+		if err = enc.Encode(hr); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// ReadHistory reads the build history file at path
+func ReadHistory(path string) (h History, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		var hr HistoryRecord
+		if err = json.Unmarshal(scanner.Bytes(), &hr); err != nil {
+			return nil, err
+		}
+		h = append(h, hr)
+	}
+
+	return h, scanner.Err()
+}
+
+// ForBuild returns the records of the build config name
+func (h History) ForBuild(name string) (records History) {
+	for _, r := range h {
+		if r.Name == name {
+			records = append(records, r)
+		}
+	}
+
+	return
+}
+
+// Since returns the records of the builds run after t
+func (h History) Since(t time.Time) (records History) {
+	for _, r := range h {
+		if r.Time.After(t) {
+			records = append(records, r)
+		}
+	}
+
+	return
+}
+
+// Succeeded returns the records of the successful builds
+func (h History) Succeeded() (records History) {
+	for _, r := range h {
+		if r.Error == "" {
+			records = append(records, r)
+		}
+	}
+
+	return
+}
+
+// Durations returns the duration of every build, oldest first
+func (h History) Durations() []time.Duration {
+	d := make([]time.Duration, len(h))
+	for i, r := range h {
+		d[i] = r.Duration
+	}
+
+	return d
+}
+
+// LayerSizes returns the sizes of the layer mid in the builds which output
+// it, oldest first
+func (h History) LayerSizes(mid string) (sizes []int64) {
+	for _, r := range h {
+		if s, ok := r.Layers[mid]; ok {
+			sizes = append(sizes, s)
+		}
+	}
+
+	return
+}
+
+// FailureRate returns the ratio of failed builds
+func (h History) FailureRate() float64 {
+	if len(h) == 0 {
+		return 0
+	}
+
+	return float64(len(h)-len(h.Succeeded())) / float64(len(h))
+}
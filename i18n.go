@@ -0,0 +1,249 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// I18nIssue describes a translation of an nls bundle whose keys differ from
+// the root bundle
+type I18nIssue struct {
+	Bundle  string   // Root bundle path, relative to SrcDir
+	Locale  string   // Locale of the translation
+	Missing []string // Keys of the root bundle missing from the translation (all of them if the translation file is missing)
+	Extra   []string // Keys of the translation not in the root bundle
+}
+
+func (i I18nIssue) String() string {
+	s := fmt.Sprintf("%s [%s]:", i.Bundle, i.Locale)
+	if len(i.Missing) > 0 {
+		s += " missing " + strings.Join(i.Missing, ", ")
+	}
+	if len(i.Extra) > 0 {
+		if len(i.Missing) > 0 {
+			s += ";"
+		}
+		s += " extra " + strings.Join(i.Extra, ", ")
+	}
+
+	return s
+}
+
+// CheckI18n compares the root nls bundles of the application packages (all
+// the packages but dojo, dijit, dojox and util) of the build config name
+// with their translations and returns the missing and extra keys per locale.
+func (c *Config) CheckI18n(name string) (issues []I18nIssue, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	for _, dir := range lintPaths(c.SrcDir, c.sourcePackages(bc.Packages), nil) {
+		err = filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if f.IsDir() || filepath.Ext(path) != ".js" || filepath.Base(filepath.Dir(path)) != "nls" {
+				return nil
+			}
+
+			bi, err := c.checkBundle(path)
+			issues = append(issues, bi...)
+
+			return err
+		})
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// checkBundle checks the translations of the root bundle at path, ignoring
+// the files of the nls directories which are not root bundles
+func (c *Config) checkBundle(path string) (issues []I18nIssue, err error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	bundle, err := jsObjectEntries(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	root, ok := bundle["root"]
+	if !ok {
+		return
+	}
+
+	rootEntries, err := jsObjectEntries(root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: root: %s", path, err)
+	}
+
+	rel, _ := filepath.Rel(c.SrcDir, path)
+	nlsDir, file := filepath.Split(path)
+
+	// Translations are both the locales flagged in the root bundle and the
+	// locale directories found next to it
+	locales := map[string]bool{}
+	for l, v := range bundle {
+		if l != "root" && strings.TrimSpace(v) != "false" {
+			locales[l] = true
+		}
+	}
+	if fis, rerr := ioutil.ReadDir(nlsDir); rerr == nil {
+		for _, fi := range fis {
+			if fi.IsDir() {
+				if _, serr := os.Stat(filepath.Join(nlsDir, fi.Name(), file)); serr == nil {
+					locales[fi.Name()] = true
+				}
+			}
+		}
+	}
+
+	for l := range locales {
+		issue := I18nIssue{Bundle: rel, Locale: l}
+
+		src, rerr := ioutil.ReadFile(filepath.Join(nlsDir, l, file))
+		if rerr != nil {
+			issue.Missing = sortedKeys(rootEntries)
+			issues = append(issues, issue)
+			continue
+		}
+
+		entries, perr := jsObjectEntries(string(src))
+		if perr != nil {
+			return nil, fmt.Errorf("%s: %s", filepath.Join(nlsDir, l, file), perr)
+		}
+
+		for k := range rootEntries {
+			if _, ok := entries[k]; !ok {
+				issue.Missing = append(issue.Missing, k)
+			}
+		}
+		for k := range entries {
+			if _, ok := rootEntries[k]; !ok {
+				issue.Extra = append(issue.Extra, k)
+			}
+		}
+
+		if len(issue.Missing) > 0 || len(issue.Extra) > 0 {
+			sort.Strings(issue.Missing)
+			sort.Strings(issue.Extra)
+			issues = append(issues, issue)
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Locale < issues[j].Locale })
+
+	return
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// jsObjectEntries returns the keys of the first object literal of src with
+// the source of their values. It only understands what nls bundles are made
+// of: identifier or string keys and literal values.
+func jsObjectEntries(src string) (entries map[string]string, err error) {
+	start := strings.IndexByte(src, '{')
+	if start < 0 {
+		return nil, errors.New("No object literal found")
+	}
+
+	entries = map[string]string{}
+
+	depth, expectKey := 0, false
+	key, valueStart := "", -1
+
+	for i := start; i < len(src); i++ {
+		ch := src[i]
+
+		switch {
+		case ch == '"' || ch == '\'' || ch == '`':
+			end := skipJSString(src, i)
+			if depth == 1 && expectKey {
+				key = src[i+1 : end-1]
+				expectKey = false
+			}
+			i = end - 1
+		case ch == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, errors.New("Unterminated comment")
+			}
+			i += end + 3
+		case ch == '{' || ch == '[' || ch == '(':
+			depth++
+			if depth == 1 {
+				expectKey = true
+			}
+		case ch == '}' || ch == ']' || ch == ')':
+			if depth == 1 && key != "" && valueStart >= 0 {
+				entries[key] = src[valueStart:i]
+			}
+			depth--
+			if depth == 0 {
+				return entries, nil
+			}
+		case depth == 1 && ch == ':' && key != "" && valueStart < 0:
+			valueStart = i + 1
+		case depth == 1 && ch == ',':
+			if key != "" && valueStart >= 0 {
+				entries[key] = src[valueStart:i]
+			}
+			key, valueStart, expectKey = "", -1, true
+		case depth == 1 && expectKey && isJSIdentChar(ch):
+			j := i
+			for j < len(src) && isJSIdentChar(src[j]) {
+				j++
+			}
+			key = src[i:j]
+			expectKey = false
+			i = j - 1
+		}
+	}
+
+	return nil, errors.New("Unterminated object literal")
+}
+
+// skipJSString returns the index following the string literal starting at i
+func skipJSString(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		if src[j] == '\\' {
+			j++
+		} else if src[j] == quote {
+			return j + 1
+		}
+	}
+
+	return len(src)
+}
+
+// isJSIdentChar tells if ch may be part of an identifier, the bytes of the
+// UTF-8 encoding of non-ASCII letters included
+func isJSIdentChar(ch byte) bool {
+	return ch >= 0x80 || ch == '_' || ch == '$' || ch == '-' || (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
@@ -0,0 +1,130 @@
+package dojoBuilder
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// inlineMIMETypes are the content types of the resources InlineResourcesMaxSize
+// inlines, by extension
+var inlineMIMETypes = map[string]string{
+	".png":   "image/png",
+	".gif":   "image/gif",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".svg":   "image/svg+xml",
+	".webp":  "image/webp",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+}
+
+// layerStylesheets returns the paths of the stylesheets the modules of the
+// layers load through a css! plugin
+func (c *Config) layerStylesheets(bc BuildConfig, layers []LayerResult) ([]string, error) {
+	res := NewResolver(c.DestDir, releasePackages(bc.Packages), nil)
+	seen := make(map[string]bool)
+	var stylesheets []string
+
+	for _, l := range layers {
+		if l.Err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(l.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		for mod, src := range layerModuleSources(b) {
+			for _, s := range cssDependencies(mod, src) {
+				if p, err := res.Path(s); err == nil && !seen[p] {
+					seen[p] = true
+					stylesheets = append(stylesheets, p)
+				}
+			}
+		}
+	}
+
+	sort.Strings(stylesheets)
+
+	return stylesheets, nil
+}
+
+// inlineLayerResources inlines the small resources of the stylesheets loaded
+// by the layers of the build config bc
+func (c *Config) inlineLayerResources(bc BuildConfig, layers []LayerResult) error {
+	stylesheets, err := c.layerStylesheets(bc, layers)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range stylesheets {
+		if err = inlineCSSResources(p, bc.InlineResourcesMaxSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inlineDirResources inlines the small resources of every stylesheet of dir
+func inlineDirResources(dir string, maxSize int64) error {
+	return filepath.Walk(dir, func(p string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() || filepath.Ext(p) != ".css" {
+			return err
+		}
+
+		return inlineCSSResources(p, maxSize)
+	})
+}
+
+// inlineCSSResources replaces the relative urls of the stylesheet at p to
+// images and fonts of at most maxSize bytes with data URIs. The urls with a
+// query or a fragment (e.g. the "?#iefix" of the font-face hacks) are kept.
+func inlineCSSResources(p string, maxSize int64) error {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(p)
+	css := string(b)
+	inlined := false
+
+	css = cssURLRegexp.ReplaceAllStringFunc(css, func(u string) string {
+		m := cssURLRegexp.FindStringSubmatch(u)
+		ref := strings.TrimSpace(m[2])
+
+		mimeType, ok := inlineMIMETypes[strings.ToLower(filepath.Ext(ref))]
+		if !ok || isAbsoluteURL(ref) || strings.ContainsAny(ref, "?#") {
+			return u
+		}
+
+		rp := filepath.Join(dir, filepath.FromSlash(ref))
+		fi, err := os.Stat(rp)
+		if err != nil || fi.Size() > maxSize {
+			return u
+		}
+
+		data, err := ioutil.ReadFile(rp)
+		if err != nil {
+			return u
+		}
+
+		inlined = true
+
+		return `url("data:` + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data) + `")`
+	})
+
+	if !inlined {
+		return nil
+	}
+
+	return ioutil.WriteFile(p, []byte(css), 0664)
+}
@@ -27,6 +27,10 @@ var (
 func SetInstallExcludeFunc(exFunc ExcludeFunc) { installExcludeFunc = exFunc }
 
 func (c *Config) installFiles() (err error) {
+	exclude, err := namedExcludeFunc(c.InstallExcludes, installExcludeFunc, nil)
+	if err != nil {
+		return
+	}
 
 	// Delete obsolete symlink and folders
 	err = filepath.Walk(c.DestDir, func(path string, f os.FileInfo, err error) (_err error) {
@@ -36,9 +40,12 @@ func (c *Config) installFiles() (err error) {
 			return nil
 		}
 
-		srcPath := c.SrcDir + path[len(c.DestDir):]
+		srcPath, _err := destPath(c.DestDir, c.SrcDir, path)
+		if _err != nil {
+			return
+		}
 
-		if forceRemove, _err = installExcludeFunc(srcPath, f); _err != nil {
+		if forceRemove, _err = exclude(srcPath, f); _err != nil {
 			return _err
 		}
 
@@ -61,15 +68,18 @@ func (c *Config) installFiles() (err error) {
 			return nil
 		}
 
-		newPath := c.DestDir + path[len(c.SrcDir):]
+		newPath, _err := destPath(c.SrcDir, c.DestDir, path)
+		if _err != nil {
+			return
+		}
 		if _, err = os.Stat(newPath); err == nil {
 			return
 		}
 
 		isDir := f.IsDir()
 
-		if skip, _err := installExcludeFunc(path, f); _err != nil {
-			return err
+		if skip, _err := exclude(path, f); _err != nil {
+			return _err
 		} else if skip {
 			if isDir {
 				return filepath.SkipDir
@@ -86,11 +96,11 @@ func (c *Config) installFiles() (err error) {
 			}
 
 			// fmt.Printf("Path : %s\nPoints to : %s\n\n", path, origPath)
-			if _err = os.Symlink(origPath, c.DestDir+path[len(c.SrcDir):]); _err != nil {
+			if _err = os.Symlink(origPath, newPath); _err != nil {
 				return _err
 			}
 		} else {
-			if _err = os.Link(path, c.DestDir+path[len(c.SrcDir):]); _err != nil {
+			if _err = os.Link(path, newPath); _err != nil {
 				return _err
 			}
 		}
@@ -0,0 +1,116 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+)
+
+var (
+	layerModuleRegexp   = regexp.MustCompile(`['"]([^'"\s:]+)['"]\s*:\s*function`)
+	layerInternedRegexp = regexp.MustCompile(`['"]url:([^'"]+)['"]\s*:`)
+	textPluginRegexp    = regexp.MustCompile(`['"]dojo/text!([^'"!]+)['"]`)
+)
+
+// InternReport lists the templates interned into the layers of a build and
+// the ones still fetched at runtime by modules of these layers.
+type InternReport struct {
+	Interned    map[string][]string // Layer module id => interned template ids
+	NotInterned map[string][]string // Layer module id => templates required by the layer modules but interned in no layer
+}
+
+// InternReport inspects the layers of the build config name output in
+// DestDir and reports which templates were interned.
+func (c *Config) InternReport(name string) (r *InternReport, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	r = &InternReport{
+		Interned:    make(map[string][]string),
+		NotInterned: make(map[string][]string),
+	}
+
+	src := NewResolver(c.SrcDir, c.sourcePackages(bc.Packages), bc.Map).WithAliases(bc.Aliases)
+	interned := make(map[string]bool)
+	modules := make(map[string][]string)
+
+	for mid := range bc.Layers {
+		p, err := c.builtLayer(name, bc, mid)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range layerInternedRegexp.FindAllSubmatch(b, -1) {
+			interned[string(m[1])] = true
+			r.Interned[mid] = append(r.Interned[mid], string(m[1]))
+		}
+
+		for _, m := range layerModuleRegexp.FindAllSubmatch(b, -1) {
+			modules[mid] = append(modules[mid], string(m[1]))
+		}
+	}
+
+	for layer, mids := range modules {
+		missing := make(map[string]bool)
+
+		for _, mid := range mids {
+			p, err := src.Path(mid)
+			if err != nil {
+				continue
+			}
+
+			b, err := ioutil.ReadFile(p)
+			if err != nil {
+				continue
+			}
+
+			for _, m := range textPluginRegexp.FindAllSubmatch(b, -1) {
+				t := src.Map(string(m[1]), mid)
+				if !interned[t] {
+					missing[t] = true
+				}
+			}
+		}
+
+		for t := range missing {
+			r.NotInterned[layer] = append(r.NotInterned[layer], t)
+		}
+		sort.Strings(r.NotInterned[layer])
+	}
+
+	for layer := range r.Interned {
+		sort.Strings(r.Interned[layer])
+	}
+
+	return
+}
+
+// Print writes a human readable version of the report to w
+func (r *InternReport) Print(w io.Writer) {
+	layers := make([]string, 0, len(r.Interned))
+	for l := range r.Interned {
+		layers = append(layers, l)
+	}
+	for l := range r.NotInterned {
+		if _, ok := r.Interned[l]; !ok {
+			layers = append(layers, l)
+		}
+	}
+	sort.Strings(layers)
+
+	for _, l := range layers {
+		fmt.Fprintf(w, "Layer %s: %d interned templates\n", l, len(r.Interned[l]))
+		for _, t := range r.NotInterned[l] {
+			fmt.Fprintf(w, "  not interned: %s\n", t)
+		}
+	}
+}
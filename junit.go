@@ -0,0 +1,118 @@
+package dojoBuilder
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnitReport writes results to w as a JUnit XML report, with one test
+// suite per build config containing a test case for the build and one for
+// each layer.
+func WriteJUnitReport(w io.Writer, results []*BuildResult) error {
+	report := junitTestSuites{}
+
+	for _, r := range results {
+		className := "dojoBuilder." + r.Name
+		suite := junitTestSuite{Name: r.Name, Time: fmt.Sprintf("%.3f", r.Duration.Seconds())}
+
+		build := junitTestCase{
+			ClassName: className,
+			Name:      "build",
+			Time:      suite.Time,
+			SystemOut: fmt.Sprintf("%d warnings, %d errors", r.Warnings, r.Errors),
+		}
+		if r.Err != nil {
+			build.Failure = &junitFailure{Message: r.Err.Error()}
+		}
+		suite.Cases = append(suite.Cases, build)
+
+		for _, l := range r.Layers {
+			tc := junitTestCase{ClassName: className, Name: "layer " + l.Name, Time: "0"}
+			if l.Err != nil {
+				tc.Failure = &junitFailure{Message: l.Err.Error()}
+			} else {
+				tc.SystemOut = fmt.Sprintf("%s (%d bytes)", l.Path, l.Size)
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		if r.Tests != nil {
+			tc := junitTestCase{
+				ClassName: className,
+				Name:      "tests",
+				Time:      "0",
+				SystemOut: fmt.Sprintf("%d tests, %d failures", r.Tests.Tests, r.Tests.Failures),
+			}
+			if r.Tests.Failures > 0 {
+				tc.Failure = &junitFailure{Message: fmt.Sprintf("%d/%d tests failed", r.Tests.Failures, r.Tests.Tests)}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		suite.Tests = len(suite.Cases)
+		for _, tc := range suite.Cases {
+			if tc.Failure != nil {
+				suite.Failures++
+			}
+		}
+
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+// WriteJUnitReportFile writes results as a JUnit XML report into the file at path
+func WriteJUnitReportFile(path string, results []*BuildResult) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return WriteJUnitReport(f, results)
+}
@@ -0,0 +1,132 @@
+package dojoBuilder
+
+import (
+	"sort"
+)
+
+// LayerCondition includes or excludes modules of a layer according to the
+// has-features of the build config, e.g. the touch modules of the "mobile"
+// feature profile
+type LayerCondition struct {
+	When    map[string]Feature // Has-features the build config must statically set to these values
+	Include []string           // Modules included when the condition is met (optional)
+	Exclude []string           // Modules excluded when the condition is met (optional)
+}
+
+// staticFeatures returns the has-features statically set by bc: its
+// StaticHasFeatures and its boolean Defines
+func staticFeatures(bc BuildConfig) map[string]Feature {
+	features := make(map[string]Feature, len(bc.StaticHasFeatures)+len(bc.Defines))
+	for n, v := range bc.Defines {
+		if b, ok := v.(bool); ok {
+			features[n] = Feature(b)
+		}
+	}
+	for n, f := range bc.StaticHasFeatures {
+		features[n] = f
+	}
+
+	return features
+}
+
+// featuresMatch reports whether all the has-features of when are set to
+// their value in features. A feature not statically set never matches.
+func featuresMatch(features, when map[string]Feature) bool {
+	for n, f := range when {
+		if v, ok := features[n]; !ok || v != f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasLayerConditions reports whether a layer of bc has a When or Conditions
+func hasLayerConditions(bc BuildConfig) bool {
+	for _, l := range bc.Layers {
+		if l.When != nil || len(l.Conditions) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveLayerConditions returns bc without the layers whose When is not
+// met, skipped, nor their LayerDests, the modules of the Conditions met being
+// added to the others
+func resolveLayerConditions(bc BuildConfig) (rbc BuildConfig, skipped []string) {
+	if !hasLayerConditions(bc) {
+		return bc, nil
+	}
+
+	features := staticFeatures(bc)
+
+	mids := make([]string, 0, len(bc.Layers))
+	for mid := range bc.Layers {
+		mids = append(mids, mid)
+	}
+	sort.Strings(mids)
+
+	layers := make(map[string]Layer, len(bc.Layers))
+	for _, mid := range mids {
+		l := bc.Layers[mid]
+
+		if !featuresMatch(features, l.When) {
+			skipped = append(skipped, mid)
+			continue
+		}
+
+		conditions := l.Conditions
+		l.When, l.Conditions = nil, nil
+		l.Include = append([]string(nil), l.Include...)
+		l.Exclude = append([]string(nil), l.Exclude...)
+
+		for _, c := range conditions {
+			if featuresMatch(features, c.When) {
+				l.Include = append(l.Include, c.Include...)
+				l.Exclude = append(l.Exclude, c.Exclude...)
+			}
+		}
+
+		layers[mid] = l
+	}
+
+	bc.Layers = layers
+
+	if len(skipped) > 0 && len(bc.LayerDests) > 0 {
+		dests := make(map[string]string, len(bc.LayerDests))
+		for mid, d := range bc.LayerDests {
+			dests[mid] = d
+		}
+		for _, mid := range skipped {
+			delete(dests, mid)
+		}
+		bc.LayerDests = dests
+	}
+
+	return bc, skipped
+}
+
+// withLayerConditions returns a copy of c whose build config name has its
+// layer conditions resolved, see resolveLayerConditions, and the layers
+// skipped. It returns c itself when the build config has no condition.
+func (c *Config) withLayerConditions(name string) (*Config, []string) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok || !hasLayerConditions(bc) {
+		return c, nil
+	}
+
+	bc, skipped := resolveLayerConditions(bc)
+
+	configs := make(map[string]BuildConfig, len(c.BuildConfigs))
+	for n, cbc := range c.BuildConfigs {
+		configs[n] = cbc
+	}
+	configs[name] = bc
+
+	rc := *c
+	rc.BuildConfigs = configs
+
+	return &rc, skipped
+}
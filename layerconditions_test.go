@@ -0,0 +1,84 @@
+package dojoBuilder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFeaturesMatch(t *testing.T) {
+	features := map[string]Feature{"mobile": true, "ie": false}
+
+	tests := []struct {
+		name string
+		when map[string]Feature
+		want bool
+	}{
+		{name: "no condition", want: true},
+		{name: "feature set", when: map[string]Feature{"mobile": true}, want: true},
+		{name: "feature unset", when: map[string]Feature{"ie": false}, want: true},
+		{name: "all features", when: map[string]Feature{"mobile": true, "ie": false}, want: true},
+		{name: "other value", when: map[string]Feature{"mobile": false}},
+		{name: "one feature not matching", when: map[string]Feature{"mobile": true, "ie": true}},
+		{name: "feature not static", when: map[string]Feature{"touch": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := featuresMatch(features, tt.when); got != tt.want {
+				t.Errorf("featuresMatch(%v) returned %t, want %t", tt.when, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLayerConditions(t *testing.T) {
+	bc := BuildConfig{
+		Packages:          []Package{{Name: "app", Location: "app"}},
+		StaticHasFeatures: map[string]Feature{"mobile": true},
+		Defines:           map[string]interface{}{"debug": false, "level": 2},
+		Layers: map[string]Layer{
+			"app/main": {
+				Include: []string{"app/base"},
+				Conditions: []LayerCondition{
+					{When: map[string]Feature{"mobile": true}, Include: []string{"app/touch"}, Exclude: []string{"app/mouse"}},
+					{When: map[string]Feature{"mobile": false}, Include: []string{"app/desktop"}},
+					{When: map[string]Feature{"debug": false}, Exclude: []string{"app/logger"}},
+				},
+			},
+			"app/mobile":  {When: map[string]Feature{"mobile": true}},
+			"app/desktop": {When: map[string]Feature{"mobile": false}},
+			"app/level":   {When: map[string]Feature{"level": true}},
+		},
+		LayerDests: map[string]string{"app/desktop": "static/", "app/mobile": "static/"},
+	}
+
+	rbc, skipped := resolveLayerConditions(bc)
+
+	if want := []string{"app/desktop", "app/level"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("Skipped %v, want %v", skipped, want)
+	}
+
+	want := map[string]Layer{
+		"app/main":   {Include: []string{"app/base", "app/touch"}, Exclude: []string{"app/mouse", "app/logger"}},
+		"app/mobile": {},
+	}
+	if !reflect.DeepEqual(rbc.Layers, want) {
+		t.Errorf("Resolved the layers %+v, want %+v", rbc.Layers, want)
+	}
+
+	if want := map[string]string{"app/mobile": "static/"}; !reflect.DeepEqual(rbc.LayerDests, want) {
+		t.Errorf("Resolved the LayerDests %v, want %v", rbc.LayerDests, want)
+	}
+	if len(bc.LayerDests) != 2 || len(bc.Layers["app/main"].Include) != 1 {
+		t.Errorf("resolveLayerConditions changed the build config")
+	}
+
+	// The LayerDests of the skipped layers are not moved
+	if _, err := (&Config{}).layerDestMoves(rbc); err != nil {
+		t.Errorf("layerDestMoves: %s", err)
+	}
+
+	if rbc, skipped := resolveLayerConditions(BuildConfig{Layers: map[string]Layer{"app/main": {}}}); skipped != nil || len(rbc.Layers) != 1 {
+		t.Errorf("resolveLayerConditions of a build config without condition returned %+v, %v", rbc.Layers, skipped)
+	}
+}
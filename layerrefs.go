@@ -0,0 +1,149 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	layerURLCallRegexp = regexp.MustCompile(`layerURL\s+"([^"]+)"`)
+	jsRefRegexp        = regexp.MustCompile(`["'(=]\s*([^"'()\s<>{}]+\.js)(?:[?#][^"'()\s<>]*)?\s*["')>]`)
+)
+
+// LayerReference is a reference of a page to a layer or a script of the
+// release
+type LayerReference struct {
+	File string // Page, relative to SrcDir
+	Ref  string // Layer module id or script path as written in the page
+}
+
+// LayerReferences lists the layer issues found in the pages of a build
+type LayerReferences struct {
+	Missing      []LayerReference // References to layers or release scripts which are not built
+	Unreferenced []string         // Module ids of the built layers no page references
+}
+
+// LayerReferences scans the HTMLFiles of the build config name, HTML pages or
+// server templates (JSP, Go templates...), for references to its layers
+// output in DestDir: layerURL calls of LayoutFuncs, module ids of the layers
+// and script paths. A script path is checked when it ends with the path of
+// a package of the build config, whatever its prefix. The references built
+// at runtime are missed, so unreferenced layers may still be used.
+func (c *Config) LayerReferences(name string) (*LayerReferences, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	packages := make(map[string]bool)
+	for _, p := range releasePackages(bc.Packages) {
+		packages[filepath.ToSlash(p.Location)] = true
+	}
+
+	// Layer module id and release path (relative to DestDir) => layer
+	layers := make(map[string]string)
+	for _, l := range c.builtLayers(bc) {
+		layers[l.Name] = l.Name
+		if l.Err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(c.DestDir, l.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			layers[filepath.ToSlash(rel)] = l.Name
+		}
+	}
+
+	r := &LayerReferences{}
+	referenced := make(map[string]bool)
+
+	for _, h := range bc.HTMLFiles {
+		b, err := ioutil.ReadFile(filepath.Join(c.SrcDir, h))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range layerURLCallRegexp.FindAllSubmatch(b, -1) {
+			mid := string(m[1])
+			if _, ok := bc.Layers[mid]; ok {
+				referenced[mid] = true
+			} else {
+				r.Missing = append(r.Missing, LayerReference{File: h, Ref: mid})
+			}
+		}
+
+		for _, m := range stringRegexp.FindAllSubmatch(b, -1) {
+			if _, ok := bc.Layers[string(m[1])]; ok {
+				referenced[string(m[1])] = true
+			}
+		}
+
+		for _, m := range jsRefRegexp.FindAllSubmatch(b, -1) {
+			ref := string(m[1])
+			if isAbsoluteURL(ref) && !strings.HasPrefix(ref, "/") {
+				continue
+			}
+
+			if mid, checked, found := c.resolveScriptRef(ref, layers, packages); found {
+				if mid != "" {
+					referenced[mid] = true
+				}
+			} else if checked {
+				r.Missing = append(r.Missing, LayerReference{File: h, Ref: ref})
+			}
+		}
+	}
+
+	for mid := range bc.Layers {
+		if !referenced[mid] {
+			r.Unreferenced = append(r.Unreferenced, mid)
+		}
+	}
+	sort.Strings(r.Unreferenced)
+
+	return r, nil
+}
+
+// resolveScriptRef looks for the script ref in the release, trying its
+// suffixes as the prefix of ref is the base url of the release. It returns
+// the layer ref is, if any, whether ref is a path of a package and whether
+// it was found in the release.
+func (c *Config) resolveScriptRef(ref string, layers map[string]string, packages map[string]bool) (mid string, checked, found bool) {
+	parts := strings.Split(strings.TrimPrefix(path.Clean(ref), "/"), "/")
+
+	for i := range parts {
+		suffix := strings.Join(parts[i:], "/")
+
+		if mid, ok := layers[suffix]; ok {
+			return mid, true, true
+		}
+
+		if _, err := os.Stat(filepath.Join(c.DestDir, filepath.FromSlash(suffix))); err == nil {
+			return "", true, true
+		}
+
+		for p := range packages {
+			if strings.HasPrefix(suffix, p+"/") {
+				checked = true
+			}
+		}
+	}
+
+	return "", checked, false
+}
+
+// Print writes a human readable version of the report to w
+func (r *LayerReferences) Print(w io.Writer) {
+	for _, m := range r.Missing {
+		fmt.Fprintf(w, "Warning: %s references %s which is not built\n", m.File, m.Ref)
+	}
+
+	for _, mid := range r.Unreferenced {
+		fmt.Fprintf(w, "Warning: the layer %s is referenced by no page\n", mid)
+	}
+}
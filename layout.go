@@ -0,0 +1,293 @@
+package dojoBuilder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Layouts of the release in DestDir
+const (
+	StandardLayout  = ""          // The dojo builder layout, packages at the top level of DestDir
+	FlatLayout      = "flat"      // The standard layout but layers are moved to the top level of DestDir, named after their module id
+	VersionedLayout = "versioned" // The standard layout in DestDir/v<hash of the release>
+)
+
+// versionFileName returns the name of the file of DestDir storing the
+// directory of the last versioned release of the build config name
+func versionFileName(name string) string {
+	return "dojoBuilder." + name + ".version"
+}
+
+// releaseVersion returns the directory of the versioned release of
+// releaseDir, named after the hash of its content
+func releaseVersion(releaseDir string) (string, error) {
+	sums, err := treeChecksums(releaseDir)
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s\x00%x\x00", filepath.ToSlash(p), sums[p])
+	}
+
+	return "v" + hex.EncodeToString(h.Sum(nil))[:10], nil
+}
+
+// ReleaseRoot returns the directory, relative to DestDir, of the release of
+// the build config name: "" but for the VersionedLayout.
+func (c *Config) ReleaseRoot(name string) (string, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return "", configNotFound(name)
+	}
+
+	if bc.Layout != VersionedLayout {
+		return "", nil
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(c.DestDir, versionFileName(name)))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// flatLayerName returns the file name of the layer mid in the FlatLayout
+func flatLayerName(mid string) string {
+	return strings.Replace(mid, "/", "-", -1) + ".js"
+}
+
+// layerDest returns the path the layer mid of bc is copied to when mapped by
+// its LayerDests, relative paths being joined to destDir
+func layerDest(destDir string, bc BuildConfig, mid string) (string, bool) {
+	d, ok := bc.LayerDests[mid]
+	if !ok {
+		return "", false
+	}
+
+	if strings.HasSuffix(d, "/") {
+		d += path.Base(mid) + ".js"
+	}
+
+	d = filepath.FromSlash(d)
+	if !filepath.IsAbs(d) {
+		d = filepath.Join(destDir, d)
+	}
+
+	return d, true
+}
+
+// layerDestMoves returns the destinations of the release files of the
+// layers of bc mapped by its LayerDests, by path relative to the release dir:
+// the layer files and their source maps
+func (c *Config) layerDestMoves(bc BuildConfig) (map[string]string, error) {
+	if len(bc.LayerDests) == 0 {
+		return nil, nil
+	}
+
+	res := NewResolver("", releasePackages(bc.Packages), nil)
+	moves := make(map[string]string, 2*len(bc.LayerDests))
+
+	for mid := range bc.LayerDests {
+		if _, ok := bc.Layers[mid]; !ok {
+			return nil, errors.New("LayerDests maps '" + mid + "' which is not a layer")
+		}
+
+		p, err := res.Path(mid)
+		if err != nil {
+			return nil, err
+		}
+		if bc.XDomainOnly {
+			p = xdomainPath(p)
+		}
+
+		d, _ := layerDest(c.DestDir, bc, mid)
+		moves[p] = d
+		moves[p+".map"] = d + ".map"
+	}
+
+	return moves, nil
+}
+
+// LayerURL returns the path, relative to DestDir and slash separated, of the
+// layer mid of the build config name according to its Layout and LayerDests.
+// Pages use it to load the layers whatever the layout.
+func (c *Config) LayerURL(name, mid string) (string, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return "", configNotFound(name)
+	}
+
+	root, err := c.ReleaseRoot(name)
+	if err != nil {
+		return "", err
+	}
+
+	return c.layerURL(name, bc, mid, root)
+}
+
+// layerURL returns the LayerURL of the layer mid of bc, the build config
+// name, in its release at root
+func (c *Config) layerURL(name string, bc BuildConfig, mid, root string) (string, error) {
+	if _, ok := bc.Layers[mid]; !ok {
+		return "", layerNotFound(name, mid)
+	}
+
+	if d, ok := layerDest(filepath.Join(c.DestDir, root), bc, mid); ok {
+		p, err := filepath.Rel(c.DestDir, d)
+		if err != nil || strings.HasPrefix(p, "..") {
+			return "", errors.New("The layer '" + mid + "' is not output in DestDir")
+		}
+
+		return filepath.ToSlash(p), nil
+	}
+
+	if bc.Layout == FlatLayout {
+		return flatLayerName(mid), nil
+	}
+
+	p, err := NewResolver(root, releasePackages(bc.Packages), nil).Path(mid)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(p), nil
+}
+
+// builtLayer returns the path of the layer mid of the build config name, bc,
+// output in DestDir: its LayerDests destination, its FlatLayout file once
+// the layers are flattened, or else its path in its package
+func (c *Config) builtLayer(name string, bc BuildConfig, mid string) (string, error) {
+	if _, ok := bc.Layers[mid]; !ok {
+		return "", layerNotFound(name, mid)
+	}
+
+	if d, ok := layerDest(c.DestDir, bc, mid); ok {
+		return d, nil
+	}
+
+	if bc.Layout == FlatLayout {
+		p := filepath.Join(c.DestDir, flatLayerName(mid))
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+
+	p, err := NewResolver(c.DestDir, releasePackages(bc.Packages), nil).Path(mid)
+	if err == nil && bc.XDomainOnly {
+		p = xdomainPath(p)
+	}
+
+	return p, err
+}
+
+// builtLayers returns the layers of bc output in DestDir, wherever its
+// Layout puts them
+func (c *Config) builtLayers(bc BuildConfig) []LayerResult {
+	layers := c.layerResults(bc)
+
+	if bc.Layout == FlatLayout {
+		for i, l := range layers {
+			if _, ok := bc.LayerDests[l.Name]; ok {
+				continue
+			}
+
+			layers[i].Path = filepath.Join(c.DestDir, flatLayerName(l.Name))
+			layers[i].Err = nil
+			if _, err := os.Stat(layers[i].Path); err != nil {
+				layers[i].Err = err
+			}
+		}
+	}
+
+	return layers
+}
+
+// flattenLayers moves the layers of r to the top level of DestDir, with
+// their source maps, but the ones mapped by the LayerDests of bc
+func (c *Config) flattenLayers(bc BuildConfig, r *BuildResult) error {
+	for i, l := range r.Layers {
+		if _, ok := bc.LayerDests[l.Name]; ok || l.Err != nil {
+			continue
+		}
+
+		dest := filepath.Join(c.DestDir, flatLayerName(l.Name))
+		if err := os.Rename(l.Path, dest); err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(l.Path + ".map"); err == nil {
+			if err = os.Rename(l.Path+".map", dest+".map"); err != nil {
+				return err
+			}
+		}
+
+		r.Layers[i].Path = dest
+	}
+
+	return nil
+}
+
+// LayoutFuncs returns the template functions resolving the release files of
+// the build config name according to its Layout:
+//
+//	{{layerURL "app/main"}}     the path of the layer app/main
+//	{{releaseURL "app/a.css"}}  the path of a release file
+//	{{cacheBust}}               the cacheBust of the dojoConfig, see CacheBust
+//
+// The paths are relative to DestDir and slash separated, with the
+// cache-busting query string when the build config CacheBust is set.
+func (c *Config) LayoutFuncs(name string) template.FuncMap {
+	return c.layoutFuncs(name, func() (string, error) { return c.ReleaseRoot(name) })
+}
+
+// layoutFuncs returns the LayoutFuncs of the build config name resolving the
+// release files in the release dir returned by root
+func (c *Config) layoutFuncs(name string, root func() (string, error)) template.FuncMap {
+	return template.FuncMap{
+		"layerURL": func(mid string) (string, error) {
+			bc, ok := c.BuildConfigs[name]
+			if !ok {
+				return "", configNotFound(name)
+			}
+			r, err := root()
+			if err != nil {
+				return "", err
+			}
+			p, err := c.layerURL(name, bc, mid, r)
+			if err != nil {
+				return "", err
+			}
+			q, err := c.CacheBust(name)
+			return withCacheBust(urlPath(p), q), err
+		},
+		"releaseURL": func(p string) (string, error) {
+			r, err := root()
+			if err != nil {
+				return "", err
+			}
+			q, err := c.CacheBust(name)
+			return withCacheBust(urlPath(path.Join(filepath.ToSlash(r), p)), q), err
+		},
+		"cacheBust": func() (string, error) {
+			return c.CacheBust(name)
+		},
+	}
+}
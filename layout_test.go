@@ -0,0 +1,171 @@
+package dojoBuilder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newLayoutTestConfig returns a config of the build config "a" bc, whose
+// DestDir holds a dummy layer at each of the given paths
+func newLayoutTestConfig(t *testing.T, bc BuildConfig, files ...string) *Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	c := &Config{
+		BuildMode:    true,
+		SrcDir:       filepath.Join(dir, "src"),
+		DestDir:      filepath.Join(dir, "dest"),
+		BuildConfigs: map[string]BuildConfig{"a": bc},
+	}
+
+	for _, f := range files {
+		p := filepath.Join(c.DestDir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("define([], function(){});\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return c
+}
+
+func layoutTestBuildConfig() BuildConfig {
+	return BuildConfig{
+		Packages: []Package{{Name: "app", Location: "app"}},
+		Layers: map[string]Layer{
+			"app/boot":  {Boot: true},
+			"app/main":  {},
+			"app/admin": {},
+		},
+	}
+}
+
+func TestMovedBootLayer(t *testing.T) {
+	bc := layoutTestBuildConfig()
+	bc.LayerDests = map[string]string{"app/boot": "static/boot/", "app/admin": "static/admin.js"}
+
+	c := newLayoutTestConfig(t, bc, "static/boot/boot.js", "static/admin.js", "app/main.js")
+
+	boot, layers, paths, err := c.verifyLayers("a", bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boot != "/static/boot/boot.js" {
+		t.Errorf("The browser check loads the boot layer from %s", boot)
+	}
+	if want := []string{"app/admin", "app/main"}; !reflect.DeepEqual(layers, want) {
+		t.Errorf("The browser check requires %v, want %v", layers, want)
+	}
+	if want := map[string]string{"app/admin": "/static/admin"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("The browser check paths are %v, want %v", paths, want)
+	}
+
+	if _, err = c.InternReport("a"); err != nil {
+		t.Errorf("InternReport: %s", err)
+	}
+	if _, err = c.LayerSplitSuggestions("a"); err != nil {
+		t.Errorf("LayerSplitSuggestions: %s", err)
+	}
+}
+
+func TestLayerURL(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "main.js")
+
+	tests := []struct {
+		name   string
+		layout string
+		dests  map[string]string
+		root   string
+		mid    string
+		want   string
+		err    bool
+	}{
+		{name: "standard", mid: "app/main", want: "app/main.js"},
+		{name: "flat", layout: FlatLayout, mid: "app/main", want: "app-main.js"},
+		{name: "versioned", layout: VersionedLayout, root: "v0123456789", mid: "app/main", want: "v0123456789/app/main.js"},
+		{name: "layer dest dir", dests: map[string]string{"app/main": "static/"}, mid: "app/main", want: "static/main.js"},
+		{name: "layer dest file", layout: FlatLayout, dests: map[string]string{"app/main": "static/boot.js"}, mid: "app/main", want: "static/boot.js"},
+		{name: "versioned layer dest", layout: VersionedLayout, root: "v0123456789", dests: map[string]string{"app/main": "static/"}, mid: "app/main", want: "v0123456789/static/main.js"},
+		{name: "other layer", dests: map[string]string{"app/main": "static/"}, mid: "app/admin", want: "app/admin.js"},
+		{name: "layer dest out of DestDir", dests: map[string]string{"app/main": outside}, mid: "app/main", err: true},
+		{name: "unknown layer", mid: "app/unknown", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := layoutTestBuildConfig()
+			bc.Layout, bc.LayerDests = tt.layout, tt.dests
+			c := newLayoutTestConfig(t, bc)
+
+			got, err := c.layerURL("a", bc, tt.mid, tt.root)
+			if tt.err {
+				if err == nil {
+					t.Errorf("layerURL returned %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("layerURL returned %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheBust(t *testing.T) {
+	tests := []struct {
+		name      string
+		layout    string
+		cacheBust bool
+		file      string
+		want      string
+		err       bool
+	}{
+		{name: "disabled", file: "v=0123456789\n"},
+		{name: "enabled", cacheBust: true, file: "v=0123456789\n", want: "v=0123456789"},
+		{name: "versioned", layout: VersionedLayout, cacheBust: true, file: "v=0123456789\n"},
+		{name: "not built", cacheBust: true, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := layoutTestBuildConfig()
+			bc.Layout, bc.CacheBust = tt.layout, tt.cacheBust
+			c := newLayoutTestConfig(t, bc)
+
+			if tt.file != "" {
+				if err := os.MkdirAll(c.DestDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(c.DestDir, cacheBustFileName("a")), []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := c.CacheBust("a")
+			if tt.err {
+				if err == nil {
+					t.Errorf("CacheBust returned %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CacheBust returned %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (&Config{}).CacheBust("a"); err == nil {
+		t.Errorf("CacheBust of an unknown build config returned no error")
+	}
+}
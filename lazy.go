@@ -0,0 +1,102 @@
+package dojoBuilder
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// LazyLayerServer serves the modules of SrcDir as they are, but builds the
+// layers of a build config on their first request, giving fast page loads
+// without rebuilding on every change. Built layers are cached in
+// DestDir/dojoBuilderLazy until Invalidate is called.
+type LazyLayerServer struct {
+	config *Config
+	name   string
+	layers map[string]string // Request path => layer module id
+	files  http.Handler
+
+	mu    sync.Mutex
+	built map[string]bool
+}
+
+// NewLazyLayerServer returns a LazyLayerServer for the build config name
+func (c *Config) NewLazyLayerServer(name string) (*LazyLayerServer, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	// The layers are served from the standard layout of their own DestDir,
+	// their builds being neither released nor recorded
+	lbc := bc
+	lbc.Layout, lbc.LayerDests = StandardLayout, nil
+	lbc.Deploy, lbc.Retention, lbc.Canary = nil, nil, false
+
+	lc := *c
+	lc.DestDir = filepath.Join(c.DestDir, "dojoBuilderLazy")
+	lc.SizeBaseline, lc.History, lc.JUnitReport = "", "", ""
+	lc.AuditLog, lc.AuditSink = "", nil
+	lc.BuildConfigs = map[string]BuildConfig{name: lbc}
+
+	s := &LazyLayerServer{
+		config: &lc,
+		name:   name,
+		layers: make(map[string]string),
+		files:  http.FileServer(http.Dir(c.SrcDir)),
+		built:  make(map[string]bool),
+	}
+
+	res := NewResolver("", bc.Packages, nil)
+	for mid := range bc.Layers {
+		p, err := res.Path(mid)
+		if err != nil {
+			return nil, err
+		}
+		s.layers["/"+filepath.ToSlash(p)] = mid
+	}
+
+	return s, nil
+}
+
+func (s *LazyLayerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mid, ok := s.layers[path.Clean("/"+r.URL.Path)]
+	if !ok {
+		s.files.ServeHTTP(w, r)
+		return
+	}
+
+	p, err := s.layerPath(mid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeFile(w, r, p)
+}
+
+// layerPath builds the layer mid if it is not cached and returns its path
+func (s *LazyLayerServer) layerPath(mid string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.built[mid] {
+		if err := s.config.BuildLayer(s.name, mid); err != nil {
+			return "", err
+		}
+		s.built[mid] = true
+	}
+
+	bc := s.config.BuildConfigs[s.name]
+
+	return NewResolver(s.config.DestDir, releasePackages(bc.Packages), nil).Path(mid)
+}
+
+// Invalidate drops the built layers, they are rebuilt on their next request
+func (s *LazyLayerServer) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.built = make(map[string]bool)
+}
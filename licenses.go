@@ -0,0 +1,172 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	licenseRegexp = regexp.MustCompile(`(?i)licen[cs]e|copyright|\(c\)`)
+
+	// The comments the optimizers keep in the layers
+	keptCommentRegexp = regexp.MustCompile(`/\*(?:!|\*?\s*@(?:license|preserve))[\s\S]*?\*/\n?`)
+)
+
+// ModuleLicense is a license header shared by modules
+type ModuleLicense struct {
+	Header  string
+	Modules []string
+}
+
+// leadingComment returns the comments at the start of src, before any code
+func leadingComment(src []byte) string {
+	var comments []string
+
+	for {
+		src = bytes.TrimLeft(src, " \t\r\n\ufeff")
+
+		if bytes.HasPrefix(src, []byte("/*")) {
+			end := bytes.Index(src, []byte("*/"))
+			if end < 0 {
+				break
+			}
+			comments = append(comments, string(src[:end+2]))
+			src = src[end+2:]
+		} else if bytes.HasPrefix(src, []byte("//")) {
+			end := bytes.IndexByte(src, '\n')
+			if end < 0 {
+				end = len(src)
+			}
+			comments = append(comments, strings.TrimRight(string(src[:end]), "\r"))
+			src = src[end:]
+		} else {
+			break
+		}
+	}
+
+	return strings.Join(comments, "\n")
+}
+
+// layerLicenses returns the license headers of the source files of the
+// modules of layers, the identical ones grouped
+func (c *Config) layerLicenses(name string, layers []LayerResult) ([]ModuleLicense, error) {
+	res, err := c.Resolver(name)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[string][]string)
+	seen := make(map[string]bool)
+
+	for _, l := range layers {
+		if l.Err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(l.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		for mid := range layerModuleSources(b) {
+			if seen[mid] || strings.ContainsAny(mid, "!:") {
+				continue
+			}
+			seen[mid] = true
+
+			p, err := res.Path(mid)
+			if err != nil {
+				continue
+			}
+
+			src, err := ioutil.ReadFile(p)
+			if err != nil {
+				// e.g. the module of a transpiled source
+				continue
+			}
+
+			if h := leadingComment(src); licenseRegexp.MatchString(h) {
+				modules[h] = append(modules[h], mid)
+			}
+		}
+	}
+
+	licenses := make([]ModuleLicense, 0, len(modules))
+	for h, mids := range modules {
+		sort.Strings(mids)
+		licenses = append(licenses, ModuleLicense{Header: h, Modules: mids})
+	}
+	sort.Slice(licenses, func(i, j int) bool { return licenses[i].Modules[0] < licenses[j].Modules[0] })
+
+	return licenses, nil
+}
+
+// Licenses returns the license headers of the modules built into the layers
+// of the build config name in DestDir
+func (c *Config) Licenses(name string) ([]ModuleLicense, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	return c.layerLicenses(name, c.builtLayers(bc))
+}
+
+// writeLicenses writes the license headers of the modules of the layers into
+// the LicenseFile of bc. If StripLicenses is set, the license comments kept by
+// the optimizer are moved from the layers to the LicenseFile.
+func (c *Config) writeLicenses(name string, bc BuildConfig, layers []LayerResult) error {
+	licenses, err := c.layerLicenses(name, layers)
+	if err != nil {
+		return err
+	}
+
+	var banners []string
+	seen := make(map[string]bool)
+
+	for _, l := range layers {
+		if !bc.StripLicenses || l.Err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(l.Path)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range keptCommentRegexp.FindAll(b, -1) {
+			if banner := strings.TrimSpace(string(m)); !seen[banner] {
+				seen[banner] = true
+				banners = append(banners, banner)
+			}
+		}
+
+		if sb := keptCommentRegexp.ReplaceAll(b, nil); len(sb) != len(b) {
+			if err = ioutil.WriteFile(l.Path, sb, 0664); err != nil {
+				return err
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Licenses of the modules of the %s build\n", name)
+	for _, l := range licenses {
+		fmt.Fprintf(&b, "\n== %s ==\n\n%s\n", strings.Join(l.Modules, ", "), l.Header)
+	}
+	if len(banners) > 0 {
+		fmt.Fprintf(&b, "\n== Comments stripped from the layers ==\n\n%s\n", strings.Join(banners, "\n\n"))
+	}
+
+	p := filepath.Join(c.DestDir, bc.LicenseFile)
+	if err = os.MkdirAll(filepath.Dir(p), 0754); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p, b.Bytes(), 0664)
+}
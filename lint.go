@@ -0,0 +1,134 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Linters supported by Lint
+const (
+	ESLint = "eslint"
+	JSHint = "jshint"
+)
+
+// Lint configures the lint stage run over the application packages before
+// building.
+type Lint struct {
+	Linter   string   // ESLint or JSHint
+	Bin      string   // Path of the linter executable (optional, default Linter)
+	Args     []string // Additional linter arguments (optional)
+	FailOn   string   // Lowest severity aborting the build, "error" (default) or "warn"
+	Packages []string // Names of the linted packages (optional, default all the packages but dojo, dijit, dojox and util)
+}
+
+// Exit status of the linters reporting messages, the other non-zero ones
+// meaning they failed
+var lintMessagesExitCodes = map[string]int{ESLint: 1, JSHint: 2}
+
+// Both "eslint --format compact" and "jshint --verbose" output
+// "file: line 1, col 2, message" lines, eslint prefixing the message with
+// its severity and jshint suffixing it with its code.
+var lintMessageRegexp = regexp.MustCompile(`^(.+): line (\d+), col (\d+), (?:(Error|Warning) - )?(.*?)(?: \(([EWI])\d+\))?$`)
+
+// lint runs the lint stage of bc over the sources of c and fails if any
+// message reaches the FailOn severity
+func (c *Config) lint(name string, bc BuildConfig) error {
+	l := bc.Lint
+
+	var args []string
+	switch l.Linter {
+	case ESLint:
+		args = []string{"--format", "compact"}
+	case JSHint:
+		args = []string{"--verbose"}
+	default:
+		return fmt.Errorf("Unknown linter '%s'", l.Linter)
+	}
+	args = append(args, l.Args...)
+
+	paths := lintPaths(c.SrcDir, c.sourcePackages(bc.Packages), l.Packages)
+	if len(paths) == 0 {
+		return nil
+	}
+	args = append(args, paths...)
+
+	bin := l.Bin
+	if bin == "" {
+		bin = l.Linter
+	}
+
+	fmt.Fprintf(c.stdout(), "Linting %s build packages\n", name)
+
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = c.SrcDir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	errs, warns := 0, 0
+
+	rerr := readLines(stdout, func(line string) {
+		m := lintMessageRegexp.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+
+		fmt.Fprintln(c.stdout(), line)
+
+		if m[4] == "Error" || m[6] == "E" {
+			errs++
+		} else {
+			warns++
+		}
+	})
+
+	// Linters exit with a non-zero status when they report messages, so
+	// only the counts are checked then
+	if werr := cmd.Wait(); werr != nil {
+		ee, ok := werr.(*exec.ExitError)
+		if !ok || ee.ExitCode() != lintMessagesExitCodes[l.Linter] || errs+warns == 0 {
+			return fmt.Errorf("%s failed: %s: %s", l.Linter, werr, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	if rerr != nil {
+		return fmt.Errorf("Cannot read the %s output: %s", l.Linter, rerr)
+	}
+
+	if errs > 0 || (warns > 0 && l.FailOn == "warn") {
+		return fmt.Errorf("Lint of %s build failed: %d errors, %d warnings", name, errs, warns)
+	}
+
+	return nil
+}
+
+// lintPaths returns the directories of the packages named names, or of all
+// the non toolkit packages if names is empty
+func lintPaths(srcDir string, packages []Package, names []string) (paths []string) {
+	linted := map[string]bool{}
+	for _, n := range names {
+		linted[n] = true
+	}
+
+	toolkit := map[string]bool{"dojo": true, "dijit": true, "dojox": true, "util": true}
+
+	for _, p := range packages {
+		if (len(names) == 0 && !toolkit[p.Name]) || linted[p.Name] {
+			paths = append(paths, p.Dir(srcDir))
+		}
+	}
+
+	return
+}
@@ -0,0 +1,145 @@
+package dojoBuilder
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultLiveReloadAddr is the address LiveReload clients connect to
+const DefaultLiveReloadAddr = ":35729"
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Reloader refreshes the browser after a successful watch mode build
+type Reloader interface {
+	Reload() error
+}
+
+// LiveReloadServer is a LiveReload server the browser extension or the
+// livereload.js client connect to. It is an http.Handler to be served on
+// DefaultLiveReloadAddr.
+type LiveReloadServer struct {
+	mu    sync.Mutex
+	conns map[net.Conn]*bufio.ReadWriter
+}
+
+// ServeHTTP upgrades the request to a websocket and registers the client
+func (s *LiveReloadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "LiveReload websocket expected", http.StatusBadRequest)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Websockets not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+
+	h := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(h[:]))
+
+	// The server hello is sent right away, the client one is not needed
+	hello := map[string]interface{}{
+		"command":    "hello",
+		"protocols":  []string{"http://livereload.com/protocols/official-7"},
+		"serverName": "dojoBuilder",
+	}
+	if err = writeWebsocketJSON(rw, hello); err != nil {
+		conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]*bufio.ReadWriter)
+	}
+	s.conns[conn] = rw
+	s.mu.Unlock()
+
+	// The client messages are ignored, reading only detects disconnection
+	io.Copy(ioutil.Discard, rw)
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// Reload tells all the connected clients to reload the page
+func (s *LiveReloadServer) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn, rw := range s.conns {
+		if err := writeWebsocketJSON(rw, map[string]interface{}{"command": "reload", "path": "/", "liveCSS": true}); err != nil {
+			conn.Close()
+			delete(s.conns, conn)
+		}
+	}
+
+	return nil
+}
+
+// writeWebsocketJSON writes v as JSON into an unmasked websocket text frame
+func writeWebsocketJSON(w *bufio.ReadWriter, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	header := []byte{0x81}
+	switch n := len(b); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if _, err = w.Write(append(header, b...)); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// BrowserSync reloads the browsers connected to a running browser-sync
+type BrowserSync struct {
+	URL string // Url of the browser-sync server, e.g. "http://localhost:3000"
+}
+
+func (b BrowserSync) Reload() error {
+	resp, err := http.Get(strings.TrimSuffix(b.URL, "/") + "/__browser_sync__?method=reload")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("browser-sync reload failed: " + resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// LockFileName is the name of the file of DestDir locked by the builds and
+// installs, so that two processes never write into the same DestDir
+const LockFileName = ".dojobuilder.lock"
+
+const lockPollInterval = 100 * time.Millisecond
+
+// lockDestDir takes the advisory lock of DestDir, waiting for the build
+// holding it according to LockTimeout. The returned func releases it.
+func (c *Config) lockDestDir() (unlock func(), err error) {
+	if err = os.MkdirAll(c.DestDir, 0754); err != nil {
+		return
+	}
+
+	path := filepath.Join(c.DestDir, LockFileName)
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0664)
+	if err != nil {
+		return
+	}
+
+	var deadline time.Time
+	if c.LockTimeout > 0 {
+		deadline = time.Now().Add(time.Duration(c.LockTimeout) * time.Second)
+	}
+
+	for waiting := false; ; waiting = true {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+
+		if err != syscall.EWOULDBLOCK {
+			f.Close()
+			return nil, fmt.Errorf("Cannot lock %s: %w", path, err)
+		}
+
+		if c.LockTimeout < 0 || (!deadline.IsZero() && time.Now().After(deadline)) {
+			f.Close()
+			return nil, &ErrDestDirLocked{DestDir: c.DestDir, PID: lockHolder(path)}
+		}
+
+		if c.canceled() {
+			f.Close()
+			return nil, ErrBuildCanceled
+		}
+
+		if !waiting {
+			fmt.Fprintf(c.stdout(), "Waiting: %s\n", &ErrDestDirLocked{DestDir: c.DestDir, PID: lockHolder(path)})
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+
+	// The pid of the holder is only informative
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+
+	return func() {
+		f.Truncate(0)
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// lockHolder returns the pid of the process holding the lock file at path,
+// 0 if unknown
+func lockHolder(path string) int {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+
+	return pid
+}
@@ -0,0 +1,110 @@
+package dojoBuilder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestFileName is the name of the manifest stored in release archives
+const ManifestFileName = "dojoBuilder.manifest.json"
+
+// Manifest lists the files of a release with their sha256
+type Manifest struct {
+	BuildID string            `json:"buildId,omitempty"` // Id of the Build run the release was output by, if known
+	Commit  string            `json:"commit,omitempty"`  // Git commit the release was built from, if known
+	Files   map[string]string `json:"files"`             // Path relative to the release dir => hex sha256
+}
+
+// NewManifest returns the manifest of the regular files of dir
+func NewManifest(dir string) (m *Manifest, err error) {
+	sums, err := treeChecksums(dir)
+	if err != nil {
+		return
+	}
+
+	m = &Manifest{Files: make(map[string]string, len(sums))}
+	for p, sum := range sums {
+		m.Files[filepath.ToSlash(p)] = hex.EncodeToString(sum)
+	}
+
+	return
+}
+
+// ReadManifest reads the manifest file at path
+func ReadManifest(path string) (m *Manifest, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	m = &Manifest{}
+	err = json.Unmarshal(b, m)
+
+	return
+}
+
+// Write writes the manifest into the file at path
+func (m *Manifest) Write(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0664)
+}
+
+// Verify checks the files of dir match the manifest. The manifest file itself
+// is ignored.
+func (m *Manifest) Verify(dir string) (err error) {
+	actual, err := NewManifest(dir)
+	if err != nil {
+		return
+	}
+
+	delete(actual.Files, ManifestFileName)
+
+	var problems []string
+
+	for p, sum := range m.Files {
+		if other, ok := actual.Files[p]; !ok {
+			problems = append(problems, "missing "+p)
+		} else if other != sum {
+			problems = append(problems, "checksum mismatch for "+p)
+		}
+	}
+
+	for p := range actual.Files {
+		if _, ok := m.Files[p]; !ok {
+			problems = append(problems, "unexpected "+p)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("Release does not match its manifest: %v", problems)
+	}
+
+	return
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,202 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// BuildMatrix derives a build config for every combination of the values of
+// its axes, e.g. locale sets × optimizer levels × has-feature profiles, to
+// compare optimizer settings or ship per-market bundles.
+type BuildMatrix struct {
+	Base         string       // Name of the build config the combinations are derived from
+	Axes         []MatrixAxis // Axes of the matrix, see LocaleAxis, OptimizeAxis and HasFeaturesAxis
+	NameTemplate string       // text/template of the names of the combinations, executed with the value names by axis name and the Base (optional, default "<base>-<value>-<value>...")
+}
+
+// MatrixAxis is an axis of a BuildMatrix
+type MatrixAxis struct {
+	Name   string // Name of the axis in the NameTemplate, e.g. "Locales" for {{.Locales}}
+	Values []MatrixValue
+}
+
+// MatrixValue is a value of a MatrixAxis
+type MatrixValue struct {
+	Name  string             // Name of the value in the names of the combinations
+	Apply func(*BuildConfig) // Sets the value into a copy of the base build config. Shared maps and slices must be copied before being changed.
+}
+
+// LocaleAxis returns the "Locales" axis setting the LocaleList to each of
+// the locale sets, by name
+func LocaleAxis(sets map[string][]string) MatrixAxis {
+	a := MatrixAxis{Name: "Locales"}
+
+	names := make([]string, 0, len(sets))
+	for n := range sets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		locales := append([]string(nil), sets[n]...)
+		a.Values = append(a.Values, MatrixValue{Name: n, Apply: func(bc *BuildConfig) {
+			bc.LocaleList = locales
+		}})
+	}
+
+	return a
+}
+
+// OptimizeAxis returns the "Optimize" axis setting both Optimize and
+// LayerOptimize to each of the levels, "" being named "none"
+func OptimizeAxis(levels ...string) MatrixAxis {
+	a := MatrixAxis{Name: "Optimize"}
+
+	for _, l := range levels {
+		l, name := l, l
+		if name == "" {
+			name = "none"
+		}
+		a.Values = append(a.Values, MatrixValue{Name: name, Apply: func(bc *BuildConfig) {
+			bc.Optimize, bc.LayerOptimize = l, l
+		}})
+	}
+
+	return a
+}
+
+// HasFeaturesAxis returns the "Features" axis adding each of the has-feature
+// profiles, by name, to the StaticHasFeatures
+func HasFeaturesAxis(profiles map[string]map[string]Feature) MatrixAxis {
+	a := MatrixAxis{Name: "Features"}
+
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		features := profiles[n]
+		a.Values = append(a.Values, MatrixValue{Name: n, Apply: func(bc *BuildConfig) {
+			merged := make(map[string]Feature, len(bc.StaticHasFeatures)+len(features))
+			for f, v := range bc.StaticHasFeatures {
+				merged[f] = v
+			}
+			for f, v := range features {
+				merged[f] = v
+			}
+			bc.StaticHasFeatures = merged
+		}})
+	}
+
+	return a
+}
+
+// ExpandMatrix adds the build configs of the combinations of m to
+// BuildConfigs and returns their names, in the order of the axes values.
+// The combinations share DestDir like any build configs: unless their Base
+// uses the VersionedLayout, their releases overwrite each other's.
+func (c *Config) ExpandMatrix(m BuildMatrix) (names []string, err error) {
+	base, ok := c.BuildConfigs[m.Base]
+	if !ok {
+		return nil, configNotFound(m.Base)
+	}
+
+	if len(m.Axes) == 0 {
+		return nil, errors.New("No axis in the matrix of '" + m.Base + "'")
+	}
+
+	var t *template.Template
+	if m.NameTemplate != "" {
+		if t, err = template.New("name").Option("missingkey=error").Parse(m.NameTemplate); err != nil {
+			return
+		}
+	}
+
+	axes := make(map[string]bool, len(m.Axes))
+	for _, a := range m.Axes {
+		if len(a.Values) == 0 {
+			return nil, errors.New("No value in the matrix axis '" + a.Name + "'")
+		} else if a.Name == "Base" || axes[a.Name] {
+			return nil, errors.New("Invalid matrix axis name '" + a.Name + "'")
+		}
+		axes[a.Name] = true
+	}
+
+	configs := make(map[string]BuildConfig)
+
+	// The combinations are enumerated as numbers whose digits are the
+	// value indexes, the last axis varying the fastest
+	idx := make([]int, len(m.Axes))
+	for {
+		bc := base
+		data := map[string]string{"Base": m.Base}
+		parts := []string{m.Base}
+
+		for i, a := range m.Axes {
+			v := a.Values[idx[i]]
+			if v.Apply != nil {
+				v.Apply(&bc)
+			}
+			data[a.Name] = v.Name
+			parts = append(parts, v.Name)
+		}
+
+		name := strings.Join(parts, "-")
+		if t != nil {
+			var b strings.Builder
+			if err = t.Execute(&b, data); err != nil {
+				return nil, err
+			}
+			name = b.String()
+		}
+
+		if _, ok := configs[name]; ok {
+			return nil, errors.New("The matrix of '" + m.Base + "' generates '" + name + "' twice")
+		} else if _, ok := c.BuildConfigs[name]; ok {
+			return nil, errors.New("The matrix of '" + m.Base + "' generates the existing build config '" + name + "'")
+		}
+
+		configs[name] = bc
+		names = append(names, name)
+
+		i := len(idx) - 1
+		for ; i >= 0; i-- {
+			if idx[i]++; idx[i] < len(m.Axes[i].Values) {
+				break
+			}
+			idx[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+
+	if base.Layout != VersionedLayout {
+		fmt.Fprintf(c.stdout(), "Warning: the combinations of the matrix of %s are output in the same DestDir, use the VersionedLayout to keep them all\n", m.Base)
+	}
+
+	if c.BuildConfigs == nil {
+		c.BuildConfigs = make(map[string]BuildConfig, len(configs))
+	}
+	for n, bc := range configs {
+		c.BuildConfigs[n] = bc
+	}
+
+	return
+}
+
+// BuildMatrix expands m into BuildConfigs and builds all its combinations,
+// see ExpandMatrix and Build
+func (c *Config) BuildMatrix(m BuildMatrix) ([]*BuildResult, error) {
+	names, err := c.ExpandMatrix(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Build(names)
+}
@@ -0,0 +1,139 @@
+package dojoBuilder_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tbaud0n/dojoBuilder"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	locales := dojoBuilder.LocaleAxis(map[string][]string{"us": {"en-us"}, "eu": {"fr", "de"}})
+	optimize := dojoBuilder.OptimizeAxis("", "closure")
+
+	tests := []struct {
+		name     string
+		axes     []dojoBuilder.MatrixAxis
+		template string
+		existing string
+		want     []string
+		err      string
+	}{
+		{
+			name: "one axis",
+			axes: []dojoBuilder.MatrixAxis{optimize},
+			want: []string{"a-none", "a-closure"},
+		},
+		{
+			name: "last axis varies the fastest",
+			axes: []dojoBuilder.MatrixAxis{optimize, locales},
+			want: []string{"a-none-eu", "a-none-us", "a-closure-eu", "a-closure-us"},
+		},
+		{
+			name:     "name template",
+			axes:     []dojoBuilder.MatrixAxis{locales, optimize},
+			template: "{{.Base}}.{{.Locales}}.{{.Optimize}}",
+			want:     []string{"a.eu.none", "a.eu.closure", "a.us.none", "a.us.closure"},
+		},
+		{
+			name:     "duplicate names",
+			axes:     []dojoBuilder.MatrixAxis{locales, optimize},
+			template: "{{.Base}}-{{.Locales}}",
+			err:      "generates 'a-eu' twice",
+		},
+		{
+			name:     "existing build config",
+			axes:     []dojoBuilder.MatrixAxis{optimize},
+			existing: "a-closure",
+			err:      "generates the existing build config 'a-closure'",
+		},
+		{
+			name:     "unknown axis in the name template",
+			axes:     []dojoBuilder.MatrixAxis{optimize},
+			template: "{{.Base}}-{{.Locales}}",
+			err:      "Locales",
+		},
+		{
+			name: "no axis",
+			err:  "No axis",
+		},
+		{
+			name: "axis without value",
+			axes: []dojoBuilder.MatrixAxis{{Name: "Empty"}},
+			err:  "No value in the matrix axis 'Empty'",
+		},
+		{
+			name: "axis named Base",
+			axes: []dojoBuilder.MatrixAxis{{Name: "Base", Values: optimize.Values}},
+			err:  "Invalid matrix axis name 'Base'",
+		},
+		{
+			name: "duplicate axis",
+			axes: []dojoBuilder.MatrixAxis{optimize, optimize},
+			err:  "Invalid matrix axis name 'Optimize'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{"a"}
+			if tt.existing != "" {
+				names = append(names, tt.existing)
+			}
+			c := newTestConfig(t, t.TempDir(), names...)
+
+			got, err := c.ExpandMatrix(dojoBuilder.BuildMatrix{Base: "a", Axes: tt.axes, NameTemplate: tt.template})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ExpandMatrix returned %v, want an error containing %q", err, tt.err)
+				}
+				if len(c.BuildConfigs) != len(names) {
+					t.Errorf("ExpandMatrix failed but added build configs: %d", len(c.BuildConfigs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandMatrix returned %v, want %v", got, tt.want)
+			}
+			for _, n := range tt.want {
+				if _, ok := c.BuildConfigs[n]; !ok {
+					t.Errorf("The build config %s was not added", n)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandMatrixValues(t *testing.T) {
+	c := newTestConfig(t, t.TempDir(), "a")
+
+	_, err := c.ExpandMatrix(dojoBuilder.BuildMatrix{
+		Base: "a",
+		Axes: []dojoBuilder.MatrixAxis{
+			dojoBuilder.LocaleAxis(map[string][]string{"eu": {"fr", "de"}}),
+			dojoBuilder.OptimizeAxis("", "closure"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc := c.BuildConfigs["a-eu-closure"]
+	if !reflect.DeepEqual(bc.LocaleList, []string{"fr", "de"}) {
+		t.Errorf("a-eu-closure has the locales %v", bc.LocaleList)
+	}
+	if bc.Optimize != "closure" || bc.LayerOptimize != "closure" {
+		t.Errorf("a-eu-closure has the optimizers %q and %q", bc.Optimize, bc.LayerOptimize)
+	}
+	if bc := c.BuildConfigs["a-eu-none"]; bc.Optimize != "" || bc.LayerOptimize != "" {
+		t.Errorf("a-eu-none has the optimizers %q and %q", bc.Optimize, bc.LayerOptimize)
+	}
+	if bc := c.BuildConfigs["a"]; bc.LocaleList != nil || bc.Optimize != "" {
+		t.Errorf("The base build config was changed: %+v", bc)
+	}
+}
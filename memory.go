@@ -0,0 +1,143 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySampleInterval is the interval between two samples of the memory of
+// the build processes
+var MemorySampleInterval = 500 * time.Millisecond
+
+// Heap given to the optimizer on the first retry after an out of memory
+// error when Config.MaxHeapMB is not set
+const defaultRetryHeapMB = 2048
+
+// Markers of the java and node out of memory errors
+var oomMarkers = [][]byte{[]byte("java.lang.OutOfMemoryError"), []byte("JavaScript heap out of memory")}
+
+func isOOM(b []byte) bool {
+	for _, m := range oomMarkers {
+		if bytes.Contains(b, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// oomWriter forwards to w and calls onOOM the first time an out of memory
+// error is written
+type oomWriter struct {
+	w     io.Writer
+	once  sync.Once
+	onOOM func()
+}
+
+func (o *oomWriter) Write(p []byte) (int, error) {
+	if isOOM(p) {
+		o.once.Do(o.onOOM)
+	}
+
+	return o.w.Write(p)
+}
+
+// processTree returns the process pid and its descendants with their total
+// resident memory in bytes. It relies on /proc and fails where it is missing.
+func processTree(pid int) (pids []int, rss int64, err error) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return
+	}
+	if len(stats) == 0 {
+		return nil, 0, os.ErrNotExist
+	}
+
+	children := make(map[int][]int)
+	pages := make(map[int]int64)
+
+	for _, s := range stats {
+		b, err := ioutil.ReadFile(s)
+		if err != nil {
+			// The process exited meanwhile
+			continue
+		}
+
+		// The command name may contain spaces, the fields follow its ')'
+		i := bytes.LastIndexByte(b, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(b[i+1:]))
+		if len(fields) < 22 {
+			continue
+		}
+
+		p, _ := strconv.Atoi(filepath.Base(filepath.Dir(s)))
+		ppid, _ := strconv.Atoi(fields[1])
+		children[ppid] = append(children[ppid], p)
+		pages[p], _ = strconv.ParseInt(fields[21], 10, 64)
+	}
+
+	pageSize := int64(os.Getpagesize())
+
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = append(queue[1:], children[p]...)
+		pids = append(pids, p)
+		rss += pages[p] * pageSize
+	}
+
+	return
+}
+
+// monitorPeakRSS samples the memory of the process tree of pid until done is
+// closed, then sends its peak on the returned channel (0 if unknown)
+func monitorPeakRSS(pid int, done <-chan struct{}) <-chan int64 {
+	peak := make(chan int64, 1)
+
+	go func() {
+		var max int64
+
+		ticker := time.NewTicker(MemorySampleInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, rss, err := processTree(pid); err == nil && rss > max {
+				max = rss
+			}
+
+			select {
+			case <-done:
+				peak <- max
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return peak
+}
+
+// killProcessTree kills p and, where /proc is available, its descendants
+func killProcessTree(p *os.Process) {
+	pids, _, _ := processTree(p.Pid)
+
+	p.Kill()
+
+	for _, pid := range pids {
+		if pid != p.Pid {
+			if dp, err := os.FindProcess(pid); err == nil {
+				dp.Kill()
+			}
+		}
+	}
+}
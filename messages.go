@@ -0,0 +1,114 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	builderMessageRegexp = regexp.MustCompile(`^\s*(error|warn)\((\d+)\)\s*(.*)$`)
+	messageModuleRegexp  = regexp.MustCompile(`module:\s*([^;\s]+)`)
+	messageFileRegexp    = regexp.MustCompile(`filename:\s*([^;\s]+)`)
+)
+
+// BuildMessage is an error or a warning output by the dojo builder
+type BuildMessage struct {
+	Level   string // "error" or "warn"
+	Code    int    // Dojo builder message code
+	Message string
+	Module  string // Module id the message is about (may be empty)
+	File    string // Absolute path of the file the message is about (may be empty)
+}
+
+// MessageFunc is called for every error or warning of the dojo builder output
+type MessageFunc func(m BuildMessage)
+
+var messageFunc MessageFunc
+
+func SetMessageFunc(f MessageFunc) { messageFunc = f }
+
+// parseBuilderMessage parses a line of the dojo builder output. The module
+// of the message, if any, is resolved into a file with r (which may be nil).
+func parseBuilderMessage(line string, r *Resolver) (m BuildMessage, ok bool) {
+	sm := builderMessageRegexp.FindStringSubmatch(line)
+	if sm == nil {
+		return
+	}
+
+	m.Level = sm[1]
+	m.Code, _ = strconv.Atoi(sm[2])
+	m.Message = sm[3]
+
+	if fm := messageFileRegexp.FindStringSubmatch(m.Message); fm != nil {
+		m.File = fm[1]
+	}
+
+	if mm := messageModuleRegexp.FindStringSubmatch(m.Message); mm != nil {
+		m.Module = mm[1]
+		if m.File == "" && r != nil {
+			m.File, _ = r.Path(m.Module)
+		}
+	}
+
+	return m, true
+}
+
+// workingDirPath returns p relative to the working directory when possible
+func workingDirPath(p string) string {
+	if p == "" {
+		return p
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, p); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+
+	return p
+}
+
+// GitHubAnnotations returns a MessageFunc writing the messages to w as
+// GitHub Actions workflow commands, so the CI annotates the offending files.
+func GitHubAnnotations(w io.Writer) MessageFunc {
+	escaper := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propEscaper := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+	return func(m BuildMessage) {
+		command := "warning"
+		if m.Level == "error" {
+			command = "error"
+		}
+
+		props := []string{fmt.Sprintf("title=%s", propEscaper.Replace(fmt.Sprintf("dojo build %s(%d)", m.Level, m.Code)))}
+		if m.File != "" {
+			props = append([]string{"file=" + propEscaper.Replace(workingDirPath(m.File))}, props...)
+		}
+
+		fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(props, ","), escaper.Replace(m.Message))
+	}
+}
+
+// ProblemMatcher returns a MessageFunc writing the messages to w as
+// "file:line: level(code): message" lines, the format generic CI problem
+// matchers understand.
+func ProblemMatcher(w io.Writer) MessageFunc {
+	return func(m BuildMessage) {
+		file := workingDirPath(m.File)
+		if file == "" {
+			file = "-"
+		}
+
+		level := "warning"
+		if m.Level == "error" {
+			level = "error"
+		}
+
+		fmt.Fprintf(w, "%s:1: %s(%d): %s\n", file, level, m.Code, m.Message)
+	}
+}
@@ -0,0 +1,179 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MetadataFileName is the name of the metadata sidecar written into DestDir
+const MetadataFileName = "dojoBuilder.metadata.json"
+
+// FileMetadata are the cache validation headers of a released file
+type FileMetadata struct {
+	ETag         string    `json:"etag"`
+	ModTime      time.Time `json:"mtime"`
+	ContentType  string    `json:"contentType"`
+	CacheControl string    `json:"cacheControl,omitempty"`
+}
+
+// ContentType is the Content-Type and Cache-Control of the files of an
+// extension
+type ContentType struct {
+	Type         string // Content type, e.g. "text/javascript" (optional, default Go's mime table)
+	CacheControl string // Cache-Control header, e.g. "public, max-age=31536000, immutable" (optional)
+}
+
+// ContentTypes maps the file extensions, dot included, to their ContentType
+type ContentTypes map[string]ContentType
+
+// DefaultContentTypes are the content types of the web files Go's mime
+// table misses or gets wrong on some hosts
+var DefaultContentTypes = ContentTypes{
+	".js":    {Type: "text/javascript; charset=utf-8"},
+	".mjs":   {Type: "text/javascript; charset=utf-8"},
+	".json":  {Type: "application/json"},
+	".map":   {Type: "application/json"},
+	".svg":   {Type: "image/svg+xml"},
+	".woff":  {Type: "font/woff"},
+	".woff2": {Type: "font/woff2"},
+	".wasm":  {Type: "application/wasm"},
+}
+
+// lookup returns the ContentType of the file p: the fields of ct override
+// the DefaultContentTypes, which override Go's mime table
+func (ct ContentTypes) lookup(p string) ContentType {
+	ext := strings.ToLower(path.Ext(p))
+
+	t := DefaultContentTypes[ext]
+	if o, ok := ct[ext]; ok {
+		if o.Type != "" {
+			t.Type = o.Type
+		}
+		if o.CacheControl != "" {
+			t.CacheControl = o.CacheControl
+		}
+	}
+
+	if t.Type == "" {
+		t.Type = mime.TypeByExtension(ext)
+	}
+	if t.Type == "" {
+		t.Type = "application/octet-stream"
+	}
+
+	return t
+}
+
+// Metadata maps the paths (relative to the release dir) of the released
+// files to their metadata
+type Metadata map[string]FileMetadata
+
+// NewMetadata returns the metadata of the regular files of dir. The ETags
+// are derived from the files sha256 so they are the same on every host.
+func NewMetadata(dir string) (Metadata, error) {
+	return NewMetadataWithTypes(dir, nil)
+}
+
+// NewMetadataWithTypes is NewMetadata with the content types and
+// Cache-Control of the files overridden by types
+func NewMetadataWithTypes(dir string, types ContentTypes) (Metadata, error) {
+	m, err := NewManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	md := make(Metadata, len(m.Files))
+
+	for p, sum := range m.Files {
+		if p == MetadataFileName {
+			continue
+		}
+
+		fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+
+		ct := types.lookup(p)
+
+		md[p] = FileMetadata{
+			ETag:         `"` + sum[:16] + `"`,
+			ModTime:      fi.ModTime().UTC().Truncate(time.Second),
+			ContentType:  ct.Type,
+			CacheControl: ct.CacheControl,
+		}
+	}
+
+	return md, nil
+}
+
+// ReadMetadata reads the metadata sidecar of dir
+func ReadMetadata(dir string) (md Metadata, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, MetadataFileName))
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(b, &md)
+
+	return
+}
+
+// Write writes the metadata sidecar into dir
+func (md Metadata) Write(dir string) error {
+	b, err := json.MarshalIndent(md, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, MetadataFileName), b, 0664)
+}
+
+// MetadataHandler returns an http.Handler serving the files of dir with the
+// ETag, Last-Modified, Content-Type and Cache-Control headers of its
+// metadata sidecar
+func MetadataHandler(dir string) (http.Handler, error) {
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := http.FileServer(http.Dir(dir))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := path.Clean("/" + r.URL.Path)[1:]
+
+		if p == MetadataFileName {
+			http.NotFound(w, r)
+			return
+		}
+
+		fm, ok := md[p]
+		if !ok {
+			fs.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Etag", fm.ETag)
+		w.Header().Set("Content-Type", fm.ContentType)
+		if fm.CacheControl != "" {
+			w.Header().Set("Cache-Control", fm.CacheControl)
+		}
+
+		http.ServeContent(w, r, p, fm.ModTime, f)
+	}), nil
+}
@@ -0,0 +1,86 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceDateEpochEnv is the environment variable giving the time (unix
+// seconds) of the files of the normalized releases, see BuildConfig.Normalize
+const SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// buildReportFileName is the name of the report written by the dojo builder
+// at the top of the release
+const buildReportFileName = "build-report.txt"
+
+// normalizedTextExts are the extensions of the files whose line endings are
+// normalized
+var normalizedTextExts = map[string]bool{
+	".js": true, ".css": true, ".html": true, ".htm": true, ".json": true,
+	".map": true, ".svg": true, ".txt": true, ".xml": true,
+}
+
+// buildReportTimeRegexps match the times and durations of the build report,
+// the dates written by JS Date.toString() and ISO 8601 ones
+var buildReportTimeRegexps = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?:Mon|Tue|Wed|Thu|Fri|Sat|Sun) (?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) \d{2} \d{4} \d{2}:\d{2}:\d{2}(?: GMT[+-]\d{4})?(?: \([^)]*\))?`), "<time>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\d+(?:\.\d+)? ?(?:seconds|ms)\b`), "<duration>"},
+}
+
+// normalizeRelease rewrites the files of releaseDir so that two builds of the
+// same sources are identical: LF line endings in text files and no times in
+// the build report
+func normalizeRelease(releaseDir string) error {
+	return filepath.Walk(releaseDir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !f.Mode().IsRegular() {
+			return nil
+		}
+
+		isReport := path == filepath.Join(releaseDir, buildReportFileName)
+		if !isReport && !normalizedTextExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		nb := bytes.Replace(b, []byte("\r\n"), []byte("\n"), -1)
+
+		if isReport {
+			for _, r := range buildReportTimeRegexps {
+				nb = r.re.ReplaceAll(nb, []byte(r.repl))
+			}
+		}
+
+		if bytes.Equal(b, nb) {
+			return nil
+		}
+
+		return ioutil.WriteFile(path, nb, f.Mode().Perm())
+	})
+}
+
+// sourceDateEpoch returns the time given by SourceDateEpochEnv, the zero
+// time if it is not set or invalid
+func sourceDateEpoch() time.Time {
+	sec, err := strconv.ParseInt(os.Getenv(SourceDateEpochEnv), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
@@ -0,0 +1,35 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// Notifier notifies the developer of the outcome of the watch mode builds
+type Notifier interface {
+	Notify(title, message string, failed bool) error
+}
+
+// DesktopNotifier shows desktop notifications with notify-send (libnotify)
+// on Linux and osascript on macOS
+type DesktopNotifier struct{}
+
+func (DesktopNotifier) Notify(title, message string, failed bool) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	default:
+		urgency := "normal"
+		if failed {
+			urgency = "critical"
+		}
+		cmd = exec.Command("notify-send", "--urgency="+urgency, "--app-name=dojoBuilder", title, message)
+	}
+
+	return cmd.Run()
+}
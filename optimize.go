@@ -0,0 +1,48 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Closure compiler compilation levels
+const (
+	WhitespaceOnly        = "WHITESPACE_ONLY"
+	SimpleOptimizations   = "SIMPLE_OPTIMIZATIONS"
+	AdvancedOptimizations = "ADVANCED_OPTIMIZATIONS"
+)
+
+// OptimizeOptions are the options given to the optimizer (profile's
+// optimizeOptions). Externs are paths relative to SrcDir (or absolute) of the
+// extern files needed by the closure advanced optimizations.
+type OptimizeOptions struct {
+	CompilationLevel string   `json:"compilationLevel,omitempty"`
+	LanguageIn       string   `json:"languageIn,omitempty"`
+	LanguageOut      string   `json:"languageOut,omitempty"`
+	Externs          []string `json:"externs,omitempty"`
+}
+
+// resolveOptimizeOptions returns a copy of o whose externs are absolute paths,
+// checking that every extern file exists.
+func (c *Config) resolveOptimizeOptions(o *OptimizeOptions) (*OptimizeOptions, error) {
+	ro := *o
+	ro.Externs = make([]string, len(o.Externs))
+
+	for i, e := range o.Externs {
+		if !filepath.IsAbs(e) {
+			e = filepath.Join(c.SrcDir, e)
+		}
+
+		fi, err := os.Stat(e)
+		if err != nil {
+			return nil, fmt.Errorf("Extern file %s not found", e)
+		} else if fi.IsDir() {
+			return nil, fmt.Errorf("Extern %s is a directory", e)
+		}
+
+		ro.Externs[i] = e
+	}
+
+	return &ro, nil
+}
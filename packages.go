@@ -0,0 +1,128 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Alias makes the loader use the module To when the module From is required
+type Alias struct {
+	From string
+	To   string
+}
+
+func (a Alias) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{a.From, a.To})
+}
+
+func (a *Alias) UnmarshalJSON(b []byte) error {
+	var v []string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	} else if len(v) != 2 {
+		return fmt.Errorf("Invalid alias %s", b)
+	}
+
+	a.From, a.To = v[0], v[1]
+
+	return nil
+}
+
+// validatePackages checks the packages and aliases of bc are consistent
+func validatePackages(bc BuildConfig) error {
+	names := make(map[string]bool, len(bc.Packages))
+
+	for _, p := range bc.Packages {
+		if p.Name == "" {
+			return fmt.Errorf("Package with location '%s' has no name", p.Location)
+		} else if names[p.Name] {
+			return fmt.Errorf("Package '%s' is defined twice", p.Name)
+		} else if p.Location == "" {
+			return fmt.Errorf("Package '%s' has no location", p.Name)
+		} else if strings.HasSuffix(p.Main, ".js") {
+			return fmt.Errorf("Main module of package '%s' must be a module id, not a file", p.Name)
+		}
+
+		names[p.Name] = true
+	}
+
+	for _, p := range bc.Packages {
+		for from, to := range p.PackageMap {
+			if !names[to] {
+				return fmt.Errorf("Package map of '%s' maps '%s' to unknown package '%s'", p.Name, from, to)
+			}
+		}
+	}
+
+	for _, a := range bc.Aliases {
+		if a.From == "" || a.To == "" {
+			return fmt.Errorf("Invalid alias [%q, %q]", a.From, a.To)
+		} else if a.From == a.To {
+			return fmt.Errorf("Alias '%s' points to itself", a.From)
+		}
+	}
+
+	return nil
+}
+
+// ReleaseLocation returns the location of the package in the release. External
+// packages are released under their name.
+func (p Package) ReleaseLocation() string {
+	if p.DestLocation != "" {
+		return p.DestLocation
+	} else if p.External() {
+		return p.Name
+	}
+
+	return p.Location
+}
+
+// External reports whether the package lives outside SrcDir, its Location
+// being an absolute path
+func (p Package) External() bool {
+	return filepath.IsAbs(p.Location)
+}
+
+// Dir returns the source directory of the package
+func (p Package) Dir(srcDir string) string {
+	if p.External() {
+		return filepath.Clean(p.Location)
+	}
+
+	return filepath.Join(srcDir, p.Location)
+}
+
+// profilePackages returns a copy of the packages whose external locations
+// are made relative to srcDir, the basePath of the profile, and released
+// under their ReleaseLocation
+func profilePackages(srcDir string, packages []Package) ([]Package, error) {
+	pp := make([]Package, len(packages))
+
+	for i, p := range packages {
+		if p.External() {
+			rel, err := filepath.Rel(srcDir, p.Location)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot locate package '%s' from %s: %s", p.Name, srcDir, err)
+			}
+			p.DestLocation = p.ReleaseLocation()
+			p.Location = filepath.ToSlash(rel)
+		}
+		pp[i] = p
+	}
+
+	return pp, nil
+}
+
+// releasePackages returns packages located where the build outputs them
+func releasePackages(packages []Package) []Package {
+	rp := make([]Package, len(packages))
+
+	for i, p := range packages {
+		p.Location = p.ReleaseLocation()
+		rp[i] = p
+	}
+
+	return rp
+}
@@ -0,0 +1,93 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PrecacheEntry is an entry of a Workbox precache manifest
+type PrecacheEntry struct {
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+}
+
+// Precache returns the Workbox precache manifest of the files of m matching
+// one of the include patterns (all of them if include is empty), their urls
+// being prefixed with baseURL and their revision being their sha256.
+func (m *Manifest) Precache(baseURL string, include ...RegExp) (entries []PrecacheEntry, err error) {
+	patterns := make([]*regexp.Regexp, len(include))
+	for i, p := range include {
+		if patterns[i], err = regexp.Compile(string(p)); err != nil {
+			return
+		}
+	}
+
+	paths := make([]string, 0, len(m.Files))
+	for p := range m.Files {
+		if p == ManifestFileName {
+			continue
+		}
+
+		matched := len(patterns) == 0
+		for _, re := range patterns {
+			if re.MatchString(p) {
+				matched = true
+				break
+			}
+		}
+
+		if matched {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	base := strings.TrimSuffix(baseURL, "/")
+	for _, p := range paths {
+		entries = append(entries, PrecacheEntry{URL: base + "/" + urlPath(p), Revision: m.Files[p]})
+	}
+
+	return
+}
+
+// WritePrecacheManifest writes entries into the file at path, as a script
+// setting self.__precacheManifest (the format of workbox-build) when path
+// ends with ".js" and as JSON otherwise
+func WritePrecacheManifest(path string, entries []PrecacheEntry) error {
+	if entries == nil {
+		entries = []PrecacheEntry{}
+	}
+
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(path, ".js") {
+		b = append(append([]byte("self.__precacheManifest = "), b...), ";\n"...)
+	}
+
+	return ioutil.WriteFile(path, b, 0664)
+}
+
+// writePrecacheManifest writes the precache manifest of the files of DestDir
+// into bc.PrecacheManifestFile, ignoring the previous precache manifest
+func (c *Config) writePrecacheManifest(bc BuildConfig) error {
+	m, err := NewManifest(c.DestDir)
+	if err != nil {
+		return err
+	}
+
+	delete(m.Files, filepath.ToSlash(filepath.Clean(bc.PrecacheManifestFile)))
+
+	entries, err := m.Precache(bc.PrecacheBaseURL, bc.PrecachePatterns...)
+	if err != nil {
+		return err
+	}
+
+	return WritePrecacheManifest(filepath.Join(c.DestDir, bc.PrecacheManifestFile), entries)
+}
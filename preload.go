@@ -0,0 +1,159 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PreloadResource is a built resource a page should preload
+type PreloadResource struct {
+	Path  string `json:"path"`            // Path relative to DestDir
+	As    string `json:"as"`              // Preload destination, "script" or "style"
+	Query string `json:"query,omitempty"` // Cache-busting query string, without "?", see CacheBust
+}
+
+// PreloadManifest lists by layer module id the resources to preload, in
+// order, for the pages loading the layer
+type PreloadManifest map[string][]PreloadResource
+
+// PreloadManifest returns the preload manifest of the layers of the build
+// config name output in DestDir. The resources of a layer are the layer
+// itself, its flattened nls bundles for the LocaleList and the stylesheets
+// its modules load through a css! plugin. The main stylesheets of the
+// Themes are added to the boot layers.
+func (c *Config) PreloadManifest(name string) (m PreloadManifest, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	res := NewResolver(c.DestDir, releasePackages(bc.Packages), nil)
+
+	query, err := c.CacheBust(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m = make(PreloadManifest)
+
+	for mid, l := range bc.Layers {
+		p, err := res.Path(mid)
+		if err != nil {
+			return nil, err
+		}
+
+		// The nls bundles are left in the layer package by the FlatLayout
+		nlsDir := filepath.Join(filepath.Dir(p), "nls")
+		if d, ok := layerDest(c.DestDir, bc, mid); ok {
+			p = d
+		} else if bc.Layout == FlatLayout {
+			p = filepath.Join(c.DestDir, flatLayerName(mid))
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		var resources []PreloadResource
+		add := func(p, as string) {
+			if _, err := os.Stat(p); err != nil {
+				return
+			}
+			if rel, err := filepath.Rel(c.DestDir, p); err == nil && !strings.HasPrefix(rel, "..") {
+				resources = append(resources, PreloadResource{Path: filepath.ToSlash(rel), As: as, Query: query})
+			}
+		}
+
+		add(p, "script")
+
+		// The dojo builder flattens the nls bundles of a layer into
+		// <layer dir>/nls/<layer name>_<locale>.js
+		for _, locale := range bc.LocaleList {
+			add(filepath.Join(nlsDir, path.Base(mid)+"_"+locale+".js"), "script")
+		}
+
+		var styles []string
+		for mod, src := range layerModuleSources(b) {
+			styles = append(styles, cssDependencies(mod, src)...)
+		}
+		sort.Strings(styles)
+
+		seen := make(map[string]bool)
+		for _, s := range styles {
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+
+			if sp, err := res.Path(s); err == nil {
+				add(sp, "style")
+			}
+		}
+
+		if l.Boot {
+			for _, t := range bc.Themes {
+				dest := t.Dest
+				if dest == "" {
+					dest = "themes/" + t.Name
+				}
+				add(filepath.Join(c.DestDir, dest, t.Name+".css"), "style")
+			}
+		}
+
+		m[mid] = resources
+	}
+
+	return
+}
+
+// cssDependencies returns the absolute module ids of the stylesheets loaded
+// by the define() call of src, the module mid, through a css! plugin
+func cssDependencies(mid string, src []byte) (styles []string) {
+	m := moduleDepsRegexp.FindSubmatch(src)
+	if m == nil {
+		return
+	}
+
+	for _, s := range stringRegexp.FindAllSubmatch(m[1], -1) {
+		parts := strings.SplitN(string(s[1]), "!", 2)
+		if len(parts) != 2 || path.Base(parts[0]) != "css" {
+			continue
+		}
+
+		style := parts[1]
+		if strings.HasPrefix(style, ".") {
+			style = path.Join(path.Dir(mid), style)
+		}
+
+		styles = append(styles, style)
+	}
+
+	return
+}
+
+// LinkHeader returns the value of the Link HTTP header preloading the
+// resources of layer, their urls being prefixed with baseURL
+func (m PreloadManifest) LinkHeader(layer, baseURL string) string {
+	links := make([]string, len(m[layer]))
+	for i, r := range m[layer] {
+		links[i] = "<" + withCacheBust(strings.TrimSuffix(baseURL, "/")+"/"+urlPath(r.Path), r.Query) + ">; rel=preload; as=" + r.As
+	}
+
+	return strings.Join(links, ", ")
+}
+
+// Write writes the manifest as JSON into the file at path
+func (m PreloadManifest) Write(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0664)
+}
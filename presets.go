@@ -0,0 +1,127 @@
+package dojoBuilder
+
+// ToolkitPackages returns the dojo, dijit and dojox packages located in the
+// directories of the same name
+func ToolkitPackages() []Package {
+	return []Package{
+		Package{Name: "dojo", Location: "dojo"},
+		Package{Name: "dijit", Location: "dijit"},
+		Package{Name: "dojox", Location: "dojox"},
+	}
+}
+
+// releaseHasFeatures are the has-features usually disabled in production
+func releaseHasFeatures() map[string]Feature {
+	return map[string]Feature{
+		"dojo-trace-api":                 false,
+		"dojo-log-api":                   false,
+		"dojo-publish-privates":          false,
+		"dojo-sync-loader":               false,
+		"dojo-test-sniff":                false,
+		"dojo-firebug":                   false,
+		"config-deferredInstrumentation": false,
+	}
+}
+
+// NewSinglePageAppConfig returns a BuildConfig for an application whose
+// code is in the package appPackage (located in the directory of the same
+// name) and which is started by entryModule. Everything is built into a
+// single boot layer dojo/dojo.
+func NewSinglePageAppConfig(appPackage, entryModule string) BuildConfig {
+	return BuildConfig{
+		RemoveUncompressed:    true,
+		RemoveConsoleStripped: true,
+		Packages:              append(ToolkitPackages(), Package{Name: appPackage, Location: appPackage}),
+		Layers: map[string]Layer{
+			"dojo/dojo": Layer{
+				Include:    []string{"dojo/dojo", entryModule},
+				CustomBase: true,
+				Boot:       true,
+			},
+		},
+		LayerOptimize:     "closure",
+		CssOptimize:       "comments",
+		Mini:              true,
+		StripConsole:      "warn",
+		SelectorEngine:    "lite",
+		StaticHasFeatures: releaseHasFeatures(),
+	}
+}
+
+// NewDojoMobileConfig returns a BuildConfig for a dojox/mobile application
+// whose code is in the package appPackage and which is started by
+// entryModule. The mobile widgets base and the application are built into
+// the boot layer dojo/dojo.
+func NewDojoMobileConfig(appPackage, entryModule string) BuildConfig {
+	bc := NewSinglePageAppConfig(appPackage, entryModule)
+
+	bc.Layers = map[string]Layer{
+		"dojo/dojo": Layer{
+			Include:    []string{"dojo/dojo", "dojox/mobile", "dojox/mobile/parser", "dojox/mobile/compat", entryModule},
+			CustomBase: true,
+			Boot:       true,
+		},
+	}
+
+	bc.StaticHasFeatures["touch"] = true
+	bc.StaticHasFeatures["dom-addeventlistener"] = true
+	bc.StaticHasFeatures["dojo-debug-messages"] = false
+
+	return bc
+}
+
+// microHasFeatures are the has-features of the loader a micro build
+// disables on top of the releaseHasFeatures: the legacy and debugging APIs
+// a bundle of a single layer never uses
+func microHasFeatures() map[string]Feature {
+	features := releaseHasFeatures()
+
+	for _, f := range []string{
+		"config-dojo-loader-catches",
+		"config-tlmSiblingOfDojo",
+		"dojo-amd-factory-scan",
+		"dojo-combo-api",
+		"dojo-config-require",
+		"dojo-debug-messages",
+		"dojo-modulePaths",
+		"dojo-moduleUrl",
+		"dojo-requirejs-api",
+		"dojo-timeout-api",
+		"dojo-undef-api",
+	} {
+		features[f] = false
+	}
+
+	features["dojo-built"] = true
+	features["host-browser"] = true
+	features["dom"] = true
+
+	return features
+}
+
+// NewMicroBuildConfig returns a BuildConfig producing the smallest dojo.js
+// for a widget embedded in third-party pages: a custom base boot layer
+// dojo/dojo with the loader, entryModule and the dojo modules it uses only,
+// the lite selector engine and the loader legacy APIs disabled. The code of
+// the widget is in the package widgetPackage; dijit and dojox are not
+// packages of the build.
+func NewMicroBuildConfig(widgetPackage, entryModule string) BuildConfig {
+	bc := BuildConfig{
+		RemoveUncompressed:    true,
+		RemoveConsoleStripped: true,
+		Packages: []Package{
+			Package{Name: "dojo", Location: "dojo"},
+			Package{Name: widgetPackage, Location: widgetPackage},
+		},
+		LayerOptimize:     "closure",
+		CssOptimize:       "comments",
+		Mini:              true,
+		StripConsole:      "all",
+		SelectorEngine:    "lite",
+		StaticHasFeatures: microHasFeatures(),
+	}
+
+	bc.SetCustomBase(entryModule)
+
+	return bc
+}
@@ -0,0 +1,112 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	regExpMarker = "dojoBuilder:regexp:"
+	jsCodeMarker = "dojoBuilder:js:"
+)
+
+var jsMarkerRegexp = regexp.MustCompile(`"(?:` + regExpMarker + `|` + jsCodeMarker + `)(?:[^"\\]|\\.)*"`)
+
+// RegExp is a regular expression written as a JS RegExp literal in the
+// profile, e.g. RegExp(`^app/templates/`) gives /^app\/templates\//
+type RegExp string
+
+func (r RegExp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(regExpMarker + string(r))
+}
+
+func (r *RegExp) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	*r = RegExp(strings.TrimPrefix(s, regExpMarker))
+
+	return nil
+}
+
+// literal returns the JS literal of r
+func (r RegExp) literal() string {
+	return "/" + escapeRegExpSlashes(string(r)) + "/"
+}
+
+// JS is some JS code, e.g. a function, written as is in the profile instead
+// of a JSON string:
+//
+//	JS("function(filename, mid){ return /\\.css$/.test(filename); }")
+type JS string
+
+func (c JS) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsCodeMarker + string(c))
+}
+
+func (c *JS) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	*c = JS(strings.TrimPrefix(s, jsCodeMarker))
+
+	return nil
+}
+
+// mergeProfileProperties returns the JSON object j with the properties of
+// extra added, replacing the ones of j with the same name
+func mergeProfileProperties(j []byte, extra map[string]interface{}) ([]byte, error) {
+	if len(extra) == 0 {
+		return j, nil
+	}
+
+	var props map[string]interface{}
+	if err := json.Unmarshal(j, &props); err != nil {
+		return nil, err
+	}
+
+	for k, v := range extra {
+		props[k] = v
+	}
+
+	return json.Marshal(props)
+}
+
+// profileJS turns the JSON of a profile into JS, replacing the RegExp
+// and JS code markers by their JS value.
+func profileJS(j []byte) []byte {
+	return jsMarkerRegexp.ReplaceAllFunc(j, func(m []byte) []byte {
+		var s string
+		if err := json.Unmarshal(m, &s); err != nil {
+			return m
+		}
+
+		if strings.HasPrefix(s, regExpMarker) {
+			return []byte(RegExp(strings.TrimPrefix(s, regExpMarker)).literal())
+		}
+
+		return []byte(strings.TrimPrefix(s, jsCodeMarker))
+	})
+}
+
+// escapeRegExpSlashes escapes the unescaped slashes of pattern
+func escapeRegExpSlashes(pattern string) string {
+	var b strings.Builder
+
+	escaped := false
+	for _, r := range pattern {
+		if r == '/' && !escaped {
+			b.WriteRune('\\')
+		}
+
+		escaped = r == '\\' && !escaped
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
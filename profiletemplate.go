@@ -0,0 +1,48 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"text/template"
+)
+
+// DefaultProfileTemplate is the template of the profile files when the
+// build config has no ProfileTemplate
+const DefaultProfileTemplate = `var profile = {{.JS}};`
+
+// ProfileData is the data of a profile template. The js template function
+// writes any value as a JS literal, e.g. {{js .Profile.packages}}.
+type ProfileData struct {
+	Name    string                 // Build config name
+	JS      string                 // The profile as a JS object literal
+	Profile map[string]interface{} // The profile properties
+	Config  BuildConfig            // The build config the profile is generated from
+}
+
+var profileTemplateFuncs = template.FuncMap{
+	"js": func(v interface{}) (string, error) {
+		j, err := json.Marshal(v)
+		return string(profileJS(j)), err
+	},
+}
+
+// parseProfileTemplate parses the ProfileTemplate of bc
+func parseProfileTemplate(bc BuildConfig) (*template.Template, error) {
+	text := bc.ProfileTemplate
+	if text == "" {
+		text = DefaultProfileTemplate
+	}
+
+	return template.New("profile").Funcs(profileTemplateFuncs).Parse(text)
+}
+
+// newProfileData returns the template data of the profile of the build
+// config name, bc, whose JSON is j
+func newProfileData(name string, bc BuildConfig, j []byte) (d ProfileData, err error) {
+	d = ProfileData{Name: name, JS: string(profileJS(j)), Config: bc}
+
+	// The RegExp and JS code markers are kept in the properties so the js
+	// function turns them back into JS
+	err = json.Unmarshal(j, &d.Profile)
+
+	return
+}
@@ -0,0 +1,106 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CopyProgress is the progress of the copy of a release into DestDir
+type CopyProgress struct {
+	Name       string // Build config name
+	File       string // File being copied, relative to DestDir, "" once the copy is done
+	Files      int    // Files copied
+	TotalFiles int
+	Bytes      int64 // Bytes copied
+	TotalBytes int64
+}
+
+// CopyProgressFunc is called before each file of a release is copied into
+// DestDir, and once the copy is done
+type CopyProgressFunc func(p CopyProgress)
+
+var copyProgressFunc CopyProgressFunc
+
+// SetCopyProgressFunc sets the func receiving the progress of the release
+// copies. The release is walked twice to compute the totals when it is set.
+func SetCopyProgressFunc(f CopyProgressFunc) { copyProgressFunc = f }
+
+// NewCopyProgressPrinter returns a CopyProgressFunc writing the progress to
+// w at most every interval, and once the copy is done
+func NewCopyProgressPrinter(w io.Writer, interval time.Duration) CopyProgressFunc {
+	var (
+		mu   sync.Mutex
+		last time.Time
+	)
+
+	return func(p CopyProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if p.File != "" && time.Since(last) < interval {
+			return
+		}
+		last = time.Now()
+
+		percent := 100.0
+		if p.TotalBytes > 0 {
+			percent = float64(p.Bytes) * 100 / float64(p.TotalBytes)
+		}
+
+		fmt.Fprintf(w, "Copying %s: %.0f%% (%.1f / %.1f MB, %d / %d files)", p.Name, percent,
+			float64(p.Bytes)/1e6, float64(p.TotalBytes)/1e6, p.Files, p.TotalFiles)
+		if p.File != "" {
+			fmt.Fprintf(w, " %s", p.File)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// copySize returns the number and size of the files of releaseDir which are
+// not excluded
+func copySize(releaseDir string, exclude ExcludeFunc) (files int, size int64, err error) {
+	err = filepath.Walk(releaseDir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if path == releaseDir {
+			return nil
+		}
+
+		if skip, err := exclude(path, f); err != nil {
+			return err
+		} else if skip {
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !f.IsDir() {
+			files++
+			size += f.Size()
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// syncPath flushes the file or directory at path to the disk
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,58 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// proxyURL returns the url of the Proxy of c, nil if none
+func (c *Config) proxyURL() (*url.URL, error) {
+	if c.Proxy == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(c.Proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid proxy url '%s'", scrub(c.Proxy))
+	}
+
+	// The proxy credentials are secrets too
+	if p, ok := u.User.Password(); ok && p != "" {
+		addScrubbed(p)
+	}
+
+	return u, nil
+}
+
+// httpClient returns the client of the requests of c, going through its
+// Proxy or the one of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables
+func (c *Config) httpClient(timeout time.Duration) (*http.Client, error) {
+	u, err := c.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if u != nil {
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: t}, nil
+}
+
+// proxyEnv returns the environment variables setting the Proxy of c for the
+// commands it runs, none if it has no Proxy: they inherit the environment
+func (c *Config) proxyEnv() ([]string, error) {
+	u, err := c.proxyURL()
+	if err != nil || u == nil {
+		return nil, err
+	}
+
+	p := u.String()
+
+	return []string{"HTTP_PROXY=" + p, "HTTPS_PROXY=" + p, "http_proxy=" + p, "https_proxy=" + p}, nil
+}
@@ -0,0 +1,98 @@
+package dojoBuilder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
+)
+
+const (
+	stubsPackageName = "dojoBuilderStubs"
+	emptyStubModule  = "define({});\n"
+)
+
+// stubNameReplacer escapes the module ids into stub names. Every "_" of a
+// stub name starting an escape, distinct module ids give distinct stubs.
+var stubNameReplacer = strings.NewReplacer("_", "_5f", "/", "_2f")
+
+// stubName returns the name of the stub module of mid in the stub package
+func stubName(mid string) string {
+	return stubNameReplacer.Replace(mid)
+}
+
+// applyReplacements adds the Replacements of bc to its "*" module map.
+// A replacement is either a module id, a js file path (relative to SrcDir) or
+// "" for an empty module. Files and empty modules are written into a stub
+// package of the profiles directory added to bc.Packages, released into
+// profiles/.
+func (c *Config) applyReplacements(name string, bc *BuildConfig) (err error) {
+	if len(bc.Replacements) == 0 {
+		return
+	}
+
+	m := make(map[string]map[string]string, len(bc.Map)+1)
+	for k, v := range bc.Map {
+		m[k] = v
+	}
+
+	star := make(map[string]string, len(m["*"])+len(bc.Replacements))
+	for k, v := range m["*"] {
+		star[k] = v
+	}
+
+	stubsDir := filepath.Join(c.profilesDir(), c.workName(stubsPackageName, name))
+	stubsLocation, err := filepath.Rel(c.SrcDir, stubsDir)
+	if err != nil {
+		return
+	}
+	needStubs := false
+
+	for mid, repl := range bc.Replacements {
+		if repl != "" && filepath.Ext(repl) != ".js" {
+			star[mid] = repl
+			continue
+		}
+
+		if !needStubs {
+			os.RemoveAll(stubsDir)
+			if err = os.MkdirAll(stubsDir, 0754); err != nil {
+				return
+			}
+			needStubs = true
+		}
+
+		stub := stubName(mid)
+		stubPath := filepath.Join(stubsDir, stub+".js")
+
+		if repl == "" {
+			f, err := os.OpenFile(stubPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+			if err != nil {
+				return err
+			}
+			_, err = f.WriteString(emptyStubModule)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		} else if err = copyutil.CopyContents(filepath.Join(c.SrcDir, repl), stubPath); err != nil {
+			return
+		}
+
+		star[mid] = stubsPackageName + "/" + stub
+	}
+
+	m["*"] = star
+	bc.Map = m
+
+	if needStubs {
+		bc.Packages = append(append([]Package{}, bc.Packages...), Package{
+			Name:         stubsPackageName,
+			Location:     filepath.ToSlash(stubsLocation),
+			DestLocation: "profiles/" + c.workName(stubsPackageName, name),
+		})
+	}
+
+	return
+}
@@ -0,0 +1,117 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// VerifyReproducibility builds names twice into separate temporary
+// directories and compares the outputs. It returns the list of the
+// differences found, which is empty if the build is deterministic.
+// Only file contents are compared, timestamps are ignored, and so are the
+// BuildInfoFileName and LockFileName files, specific to each build. The
+// verification builds are neither deployed nor recorded, and do not use the
+// CacheDir.
+func (c *Config) VerifyReproducibility(names []string) (diffs []string, err error) {
+	var dirs [2]string
+
+	for i := range dirs {
+		if dirs[i], err = ioutil.TempDir("", "dojoBuilderVerify"); err != nil {
+			return
+		}
+		defer os.RemoveAll(dirs[i])
+
+		cc := *c
+		cc.DestDir = dirs[i]
+		cc.SizeBaseline, cc.History, cc.JUnitReport, cc.CacheDir = "", "", "", ""
+		cc.AuditLog, cc.AuditSink = "", nil
+
+		cc.BuildConfigs = make(map[string]BuildConfig, len(c.BuildConfigs))
+		for n, bc := range c.BuildConfigs {
+			bc.Deploy, bc.Retention = nil, nil
+			cc.BuildConfigs[n] = bc
+		}
+
+		fmt.Fprintf(c.stdout(), "Reproducibility build %d/2\n", i+1)
+
+		if err = cc.build(names); err != nil {
+			return
+		}
+	}
+
+	first, err := treeChecksums(dirs[0])
+	if err != nil {
+		return
+	}
+
+	second, err := treeChecksums(dirs[1])
+	if err != nil {
+		return
+	}
+
+	// Their build id and time always differ
+	for _, p := range []string{BuildInfoFileName, LockFileName} {
+		delete(first, p)
+		delete(second, p)
+	}
+
+	for p, sum := range first {
+		if other, ok := second[p]; !ok {
+			diffs = append(diffs, "Only in first build: "+p)
+		} else if !bytes.Equal(sum, other) {
+			diffs = append(diffs, "Content differs: "+p)
+		}
+	}
+
+	for p := range second {
+		if _, ok := first[p]; !ok {
+			diffs = append(diffs, "Only in second build: "+p)
+		}
+	}
+
+	sort.Strings(diffs)
+
+	return
+}
+
+// treeChecksums returns the sha256 of every regular file of root by path
+// relative to root.
+func treeChecksums(root string) (sums map[string][]byte, err error) {
+	sums = make(map[string][]byte)
+
+	err = filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !f.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		h := sha256.New()
+		if _, err = io.Copy(h, file); err != nil {
+			return err
+		}
+
+		sums[rel] = h.Sum(nil)
+
+		return nil
+	})
+
+	return
+}
@@ -0,0 +1,22 @@
+package dojoBuilder_test
+
+import (
+	"testing"
+
+	"github.com/tbaud0n/dojoBuilder/buildertest"
+)
+
+func TestVerifyReproducibility(t *testing.T) {
+	c := newTestConfig(t, t.TempDir(), "a", "b")
+
+	fb := &buildertest.FakeBuilder{}
+	fb.Install()
+	defer fb.Uninstall()
+
+	diffs, err := c.VerifyReproducibility(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(diffs) > 0 {
+		t.Fatalf("Differences between identical builds: %v", diffs)
+	}
+}
@@ -0,0 +1,185 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Resolver maps AMD module ids to file paths and back, the way the dojo
+// loader does from the packages and the module map of a build config.
+type Resolver struct {
+	BaseDir string // Directory package locations are relative to
+
+	packages  []Package
+	moduleMap map[string]map[string]string
+	aliases   []Alias
+}
+
+// NewResolver returns a Resolver for packages located relatively to baseDir
+func NewResolver(baseDir string, packages []Package, moduleMap map[string]map[string]string) *Resolver {
+	r := &Resolver{
+		BaseDir:   baseDir,
+		packages:  append([]Package(nil), packages...),
+		moduleMap: moduleMap,
+	}
+
+	// Longest locations first so nested packages are found before their parent
+	sort.SliceStable(r.packages, func(i, j int) bool {
+		return len(r.packages[i].Location) > len(r.packages[j].Location)
+	})
+
+	return r
+}
+
+// Resolver returns a Resolver for the source files of the build config name
+func (c *Config) Resolver(name string) (*Resolver, error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	return NewResolver(c.SrcDir, c.sourcePackages(bc.Packages), bc.Map).WithAliases(bc.Aliases), nil
+}
+
+// WithAliases makes r resolve the module ids aliased by aliases
+func (r *Resolver) WithAliases(aliases []Alias) *Resolver {
+	r.aliases = aliases
+	return r
+}
+
+// Map applies the aliases, the package map of the package of ref and the
+// module map to mid as required by the module ref (which may be empty), and
+// resolves mid if it's relative to ref.
+func (r *Resolver) Map(mid, ref string) string {
+	if strings.HasPrefix(mid, ".") && ref != "" {
+		mid = path.Join(path.Dir(ref), mid)
+	}
+
+	for _, a := range r.aliases {
+		if a.From == mid {
+			mid = a.To
+			break
+		}
+	}
+
+	if ref != "" {
+		refPkg := strings.SplitN(ref, "/", 2)[0]
+		for _, p := range r.packages {
+			if p.Name != refPkg {
+				continue
+			}
+
+			parts := strings.SplitN(mid, "/", 2)
+			if to, ok := p.PackageMap[parts[0]]; ok {
+				parts[0] = to
+				mid = strings.Join(parts, "/")
+			}
+			break
+		}
+	}
+
+	m := r.moduleMap["*"]
+	if ref != "" {
+		refs := make([]string, 0, len(r.moduleMap))
+		for k := range r.moduleMap {
+			refs = append(refs, k)
+		}
+
+		if prefix := longestPrefix(refs, ref); prefix != "" {
+			m = r.moduleMap[prefix]
+		}
+	}
+
+	if mapped, ok := mapModuleId(m, mid); ok {
+		return mapped
+	} else if mapped, ok := mapModuleId(r.moduleMap["*"], mid); ok {
+		return mapped
+	}
+
+	return mid
+}
+
+func mapModuleId(m map[string]string, mid string) (string, bool) {
+	mids := make([]string, 0, len(m))
+	for k := range m {
+		mids = append(mids, k)
+	}
+
+	if prefix := longestPrefix(mids, mid); prefix != "" {
+		return m[prefix] + mid[len(prefix):], true
+	}
+
+	return mid, false
+}
+
+// Path returns the path of the file of the module mid. Ids with an
+// extension (e.g. templates) are resolved as is, ".js" is added otherwise.
+func (r *Resolver) Path(mid string) (string, error) {
+	mid = r.Map(mid, "")
+
+	parts := strings.SplitN(mid, "/", 2)
+
+	for _, p := range r.packages {
+		if p.Name != parts[0] {
+			continue
+		}
+
+		rel := "main"
+		if p.Main != "" {
+			rel = strings.TrimPrefix(p.Main, "./")
+		}
+		if len(parts) == 2 {
+			rel = parts[1]
+		}
+
+		if path.Ext(rel) == "" {
+			rel += ".js"
+		}
+
+		return filepath.Join(r.location(p), filepath.FromSlash(rel)), nil
+	}
+
+	return "", fmt.Errorf("No package found for module '%s'", mid)
+}
+
+// ModuleId returns the id of the module whose file is at path p
+func (r *Resolver) ModuleId(p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(r.BaseDir, p)
+	}
+
+	for _, pkg := range r.packages {
+		rel, err := filepath.Rel(r.location(pkg), p)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		rel = filepath.ToSlash(rel)
+		if path.Ext(rel) == ".js" {
+			rel = rel[:len(rel)-len(".js")]
+		}
+
+		return pkg.Name + "/" + rel, nil
+	}
+
+	return "", fmt.Errorf("No package contains '%s'", p)
+}
+
+// location returns the directory of the package p
+func (r *Resolver) location(p Package) string {
+	return p.Dir(r.BaseDir)
+}
+
+// longestPrefix returns the longest of keys which is mid or a parent of mid
+func longestPrefix(keys []string, mid string) (prefix string) {
+	for _, k := range keys {
+		if (mid == k || strings.HasPrefix(mid, k+"/")) && len(k) > len(prefix) {
+			prefix = k
+		}
+	}
+
+	return
+}
@@ -0,0 +1,101 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ResourceDir is an entry of the trees, dirs or files of a package. Src is
+// relative to the package location and Dest to its release location. Excludes
+// are patterns of the paths to ignore (trees and dirs only).
+type ResourceDir struct {
+	Src      string
+	Dest     string
+	Excludes []RegExp
+}
+
+func (r ResourceDir) MarshalJSON() ([]byte, error) {
+	v := []interface{}{r.Src, r.Dest}
+	for _, e := range r.Excludes {
+		v = append(v, e)
+	}
+
+	return json.Marshal(v)
+}
+
+func (r *ResourceDir) UnmarshalJSON(b []byte) error {
+	var v []string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	} else if len(v) < 2 {
+		return fmt.Errorf("Invalid resource directive %s", b)
+	}
+
+	r.Src, r.Dest, r.Excludes = v[0], v[1], nil
+	for _, e := range v[2:] {
+		r.Excludes = append(r.Excludes, RegExp(strings.TrimPrefix(e, regExpMarker)))
+	}
+
+	return nil
+}
+
+// WithTree returns a copy of p with the tree src (copied to dest) added
+func (p Package) WithTree(src, dest string, excludes ...RegExp) Package {
+	p.Trees = append(append([]ResourceDir(nil), p.Trees...), ResourceDir{src, dest, excludes})
+	return p
+}
+
+// WithDir returns a copy of p with the directory src (copied to dest) added
+func (p Package) WithDir(src, dest string, excludes ...RegExp) Package {
+	p.Dirs = append(append([]ResourceDir(nil), p.Dirs...), ResourceDir{src, dest, excludes})
+	return p
+}
+
+// WithFile returns a copy of p with the file src (copied to dest) added
+func (p Package) WithFile(src, dest string) Package {
+	p.Files = append(append([]ResourceDir(nil), p.Files...), ResourceDir{Src: src, Dest: dest})
+	return p
+}
+
+// Excluding returns a copy of p whose whole tree is used except the paths
+// matching excludes, e.g. dijit.Excluding(`/tests/`, `/demos/`)
+func (p Package) Excluding(excludes ...RegExp) Package {
+	return p.WithTree(".", ".", excludes...)
+}
+
+// resolveResources returns a copy of the packages whose resource paths are
+// absolute, sources in srcDir and destinations in releaseDir.
+func resolveResources(packages []Package, srcDir, releaseDir string) []Package {
+	resolved := make([]Package, len(packages))
+
+	for i, p := range packages {
+		src := p.Dir(srcDir)
+		dest := filepath.Join(releaseDir, p.ReleaseLocation())
+
+		p.Trees = resolveResourceDirs(p.Trees, src, dest)
+		p.Dirs = resolveResourceDirs(p.Dirs, src, dest)
+		p.Files = resolveResourceDirs(p.Files, src, dest)
+
+		resolved[i] = p
+	}
+
+	return resolved
+}
+
+func resolveResourceDirs(dirs []ResourceDir, src, dest string) []ResourceDir {
+	if dirs == nil {
+		return nil
+	}
+
+	resolved := make([]ResourceDir, len(dirs))
+
+	for i, d := range dirs {
+		d.Src = filepath.Join(src, d.Src)
+		d.Dest = filepath.Join(dest, d.Dest)
+		resolved[i] = d
+	}
+
+	return resolved
+}
@@ -0,0 +1,137 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// The predicates of resourceTagFunc are the patterns of a tag between
+// resourceTagFuncPrefix and resourceTagFuncSuffix
+const (
+	resourceTagFuncPrefix = "function(filename, mid){ return ["
+	resourceTagFuncSuffix = "].some(function(re){ return re.test(filename) || re.test(mid); }); }"
+)
+
+// ResourceTags tag the resources of a package for the dojo builder. A
+// resource gets a tag if its file name or module id matches one of the
+// patterns of the tag, or if the predicate of Funcs for the tag is true.
+type ResourceTags struct {
+	AMD         []RegExp // AMD modules
+	CopyOnly    []RegExp // Resources copied without any transform
+	Test        []RegExp // Test resources, discarded when the profile excludes tests
+	MiniExclude []RegExp // Resources discarded by mini builds
+
+	// Predicates function(filename, mid) by tag name ("amd", "copyOnly",
+	// "test", "miniExclude" or a custom tag), replacing the patterns of the
+	// tag (optional)
+	Funcs map[string]JS
+}
+
+func (t ResourceTags) MarshalJSON() ([]byte, error) {
+	tags := make(map[string]JS)
+
+	for name, patterns := range map[string][]RegExp{
+		"amd":         t.AMD,
+		"copyOnly":    t.CopyOnly,
+		"test":        t.Test,
+		"miniExclude": t.MiniExclude,
+	} {
+		if len(patterns) > 0 {
+			tags[name] = resourceTagFunc(patterns)
+		}
+	}
+
+	for name, f := range t.Funcs {
+		tags[name] = f
+	}
+
+	return json.Marshal(tags)
+}
+
+// UnmarshalJSON reads the predicates written by MarshalJSON, turning the
+// ones of the patterns of a standard tag back into its patterns
+func (t *ResourceTags) UnmarshalJSON(b []byte) error {
+	var tags map[string]JS
+	if err := json.Unmarshal(b, &tags); err != nil {
+		return err
+	}
+
+	*t = ResourceTags{}
+
+	for name, f := range tags {
+		patterns := map[string]*[]RegExp{
+			"amd":         &t.AMD,
+			"copyOnly":    &t.CopyOnly,
+			"test":        &t.Test,
+			"miniExclude": &t.MiniExclude,
+		}[name]
+
+		if patterns != nil {
+			if p, ok := resourceTagPatterns(f); ok {
+				*patterns = p
+				continue
+			}
+		}
+
+		if t.Funcs == nil {
+			t.Funcs = make(map[string]JS)
+		}
+		t.Funcs[name] = f
+	}
+
+	return nil
+}
+
+// resourceTagFunc returns a resource tag predicate matching patterns
+func resourceTagFunc(patterns []RegExp) JS {
+	literals := make([]string, len(patterns))
+	for i, p := range patterns {
+		literals[i] = p.literal()
+	}
+
+	return JS(resourceTagFuncPrefix + strings.Join(literals, ", ") + resourceTagFuncSuffix)
+}
+
+// resourceTagPatterns returns the patterns of the predicate f returned by
+// resourceTagFunc, false if f is another predicate
+func resourceTagPatterns(f JS) (patterns []RegExp, ok bool) {
+	s := string(f)
+	if !strings.HasPrefix(s, resourceTagFuncPrefix) || !strings.HasSuffix(s, resourceTagFuncSuffix) {
+		return nil, false
+	}
+	s = s[len(resourceTagFuncPrefix) : len(s)-len(resourceTagFuncSuffix)]
+
+	for s != "" {
+		if len(patterns) > 0 {
+			if !strings.HasPrefix(s, ", ") {
+				return nil, false
+			}
+			s = s[2:]
+		}
+
+		if !strings.HasPrefix(s, "/") {
+			return nil, false
+		}
+
+		// The literal ends with its first unescaped slash, the others being
+		// escaped by escapeRegExpSlashes
+		var b strings.Builder
+		i, escaped := 1, false
+		for ; i < len(s) && (s[i] != '/' || escaped); i++ {
+			if s[i] == '\\' && !escaped && i+1 < len(s) && s[i+1] == '/' {
+				escaped = true
+				continue
+			}
+			escaped = s[i] == '\\' && !escaped
+			b.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return nil, false
+		}
+
+		patterns = append(patterns, RegExp(b.String()))
+		s = s[i+1:]
+	}
+
+	return patterns, true
+}
@@ -0,0 +1,81 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"time"
+)
+
+// BuildResult is the result of the build of a build config
+type BuildResult struct {
+	Name     string // Build config name
+	BuildID  string // Id of the Build run
+	Commit   string // Commit built, if known, see Config.VCS
+	Dest     string // Directory the release was copied into
+	Err      error  // Build error, nil on success
+	Duration time.Duration
+	Warnings int // Number of warnings output by the dojo builder
+	Errors   int // Number of errors output by the dojo builder
+	Layers   []LayerResult
+	Tests    *TestResult   // Result of the test suite, nil if not run
+	Excluded *ExcludeStats // Paths of the release not copied into DestDir
+
+	Deployments []DeployResult // Results of the Deploy targets, in order, nil if not deployed
+
+	PeakMemory int64 // Peak resident memory (bytes) of the build processes, 0 if unknown
+}
+
+// LayerResult describes a layer output by a build
+type LayerResult struct {
+	Name string // Layer module id
+	Path string // Layer file path
+	Size int64
+	Err  error // Non nil if the layer file was not output
+}
+
+// addMessage counts m into r, which may be nil
+func (r *BuildResult) addMessage(m BuildMessage) {
+	if r == nil {
+		return
+	}
+
+	if m.Level == "error" {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// layerResults checks the layers of bc were output into DestDir
+func (c *Config) layerResults(bc BuildConfig) (layers []LayerResult) {
+	res := NewResolver(c.DestDir, releasePackages(bc.Packages), nil)
+
+	mids := make([]string, 0, len(bc.Layers))
+	for mid := range bc.Layers {
+		mids = append(mids, mid)
+	}
+	sort.Strings(mids)
+
+	for _, mid := range mids {
+		l := LayerResult{Name: mid}
+
+		if d, ok := layerDest(c.DestDir, bc, mid); ok {
+			l.Path = d
+		} else if l.Path, l.Err = res.Path(mid); l.Err == nil && bc.XDomainOnly {
+			l.Path = xdomainPath(l.Path)
+		}
+
+		if l.Err == nil {
+			if fi, err := os.Stat(l.Path); err != nil {
+				l.Err = errors.New("Layer file not found")
+			} else {
+				l.Size = fi.Size()
+			}
+		}
+
+		layers = append(layers, l)
+	}
+
+	return
+}
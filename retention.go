@@ -0,0 +1,166 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Retention limits the versioned releases of a build config kept in DestDir.
+// The current release, the canary one and the pinned ones are never removed.
+type Retention struct {
+	KeepLast int      // Number of latest releases kept, the current one included (optional)
+	Pinned   []string // Releases, by version directory name, kept whatever the other rules (optional)
+	MaxSize  int64    // Max total size (bytes) of the releases, the oldest ones being removed first (optional)
+}
+
+// versionsFileName returns the name of the file of DestDir listing the
+// versioned releases of the build config name, oldest first
+func versionsFileName(name string) string {
+	return "dojoBuilder." + name + ".versions"
+}
+
+// releaseVersions returns the versioned releases of the build config name
+// still in DestDir, oldest first
+func (c *Config) releaseVersions(name string) ([]string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(c.DestDir, versionsFileName(name)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, v := range strings.Fields(string(b)) {
+		if _, err := os.Stat(filepath.Join(c.DestDir, v)); err == nil {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, nil
+}
+
+func (c *Config) writeReleaseVersions(name string, versions []string) error {
+	var b strings.Builder
+	for _, v := range versions {
+		b.WriteString(v + "\n")
+	}
+
+	return ioutil.WriteFile(filepath.Join(c.DestDir, versionsFileName(name)), []byte(b.String()), 0664)
+}
+
+// addReleaseVersion records version as the latest release of the build
+// config name
+func (c *Config) addReleaseVersion(name, version string) error {
+	versions, err := c.releaseVersions(name)
+	if err != nil {
+		return err
+	}
+
+	kept := versions[:0]
+	for _, v := range versions {
+		if v != version {
+			kept = append(kept, v)
+		}
+	}
+
+	return c.writeReleaseVersions(name, append(kept, version))
+}
+
+// PruneReleases removes the versioned releases of the build config name
+// exceeding its Retention and returns their directories. It is run after
+// every successful build of a VersionedLayout build config with a Retention.
+// DestDir is locked meanwhile, see LockTimeout.
+func (c *Config) PruneReleases(name string) (removed []string, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	if bc.Layout != VersionedLayout || bc.Retention == nil {
+		return
+	}
+
+	if !c.locked {
+		unlock, lerr := c.lockDestDir()
+		if lerr != nil {
+			return nil, lerr
+		}
+		defer unlock()
+	}
+
+	versions, err := c.releaseVersions(name)
+	if err != nil || len(versions) == 0 {
+		return
+	}
+
+	// The current and canary releases of all the build configs may share a
+	// version
+	kept := make(map[string]bool)
+	for _, v := range bc.Retention.Pinned {
+		kept[v] = true
+	}
+	for n := range c.BuildConfigs {
+		if root, rerr := c.ReleaseRoot(n); rerr == nil && root != "" {
+			kept[root] = true
+		}
+		if root, rerr := c.CanaryRoot(n); rerr == nil && root != "" {
+			kept[root] = true
+		}
+	}
+
+	remove := make(map[string]bool)
+
+	if n := bc.Retention.KeepLast; n > 0 && len(versions) > n {
+		for _, v := range versions[:len(versions)-n] {
+			if !kept[v] {
+				remove[v] = true
+			}
+		}
+	}
+
+	if bc.Retention.MaxSize > 0 {
+		sizes := make(map[string]int64, len(versions))
+		var total int64
+		for _, v := range versions {
+			if remove[v] {
+				continue
+			}
+			if sizes[v], err = dirSize(filepath.Join(c.DestDir, v)); err != nil {
+				return
+			}
+			total += sizes[v]
+		}
+
+		for _, v := range versions {
+			if total <= bc.Retention.MaxSize {
+				break
+			}
+			if !kept[v] && !remove[v] {
+				remove[v] = true
+				total -= sizes[v]
+			}
+		}
+	}
+
+	var left []string
+	for _, v := range versions {
+		if !remove[v] {
+			left = append(left, v)
+			continue
+		}
+
+		dir := filepath.Join(c.DestDir, v)
+		if err = os.RemoveAll(dir); err != nil {
+			return
+		}
+		removed = append(removed, dir)
+		fmt.Fprintf(c.stdout(), "Removed release %s of %s\n", v, name)
+	}
+
+	err = c.writeReleaseVersions(name, left)
+
+	return
+}
@@ -0,0 +1,129 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+var packageNameRegexp = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+
+// scaffoldFiles are the templates of the files of a new package, by path
+// relative to the package directory
+var scaffoldFiles = map[string]string{
+	"package.json": `{
+	"name": "{{.}}",
+	"version": "0.1.0",
+	"main": "main"
+}
+`,
+	"main.js": `define([
+	"./Widget"
+], function(Widget){
+	return {
+		Widget: Widget
+	};
+});
+`,
+	"Widget.js": `define([
+	"dojo/_base/declare",
+	"dijit/_WidgetBase",
+	"dijit/_TemplatedMixin",
+	"dojo/text!./templates/Widget.html",
+	"dojo/i18n!./nls/Widget"
+], function(declare, _WidgetBase, _TemplatedMixin, template, messages){
+	return declare([_WidgetBase, _TemplatedMixin], {
+		templateString: template,
+		messages: messages
+	});
+});
+`,
+	"templates/Widget.html": `<div class="{{.}}Widget">${messages.greeting}</div>
+`,
+	"nls/Widget.js": `define({
+	root: {
+		greeting: "Hello"
+	}
+});
+`,
+	"tests/Widget.js": `define([
+	"doh/runner",
+	"../Widget"
+], function(doh, Widget){
+	doh.register("{{.}}/tests/Widget", [
+		function create(){
+			var w = new Widget();
+			doh.is("Hello", w.messages.greeting);
+			w.destroy();
+		}
+	]);
+});
+`,
+}
+
+// Scaffold creates the skeleton of a new AMD package name in SrcDir (package.json,
+// a sample widget with its template, nls bundle and test) and adds the package
+// to the build configs buildNames.
+func (c *Config) Scaffold(name string, buildNames []string) (err error) {
+	if !packageNameRegexp.MatchString(name) {
+		return fmt.Errorf("Invalid package name '%s'", name)
+	}
+
+	for _, n := range buildNames {
+		bc, ok := c.BuildConfigs[n]
+		if !ok {
+			return configNotFound(n)
+		}
+
+		for _, p := range bc.Packages {
+			if p.Name == name {
+				return fmt.Errorf("Package '%s' already exists in build config '%s'", name, n)
+			}
+		}
+	}
+
+	dir := filepath.Join(c.SrcDir, name)
+	if _, err = os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+
+	for rel, content := range scaffoldFiles {
+		if err = writeScaffoldFile(filepath.Join(dir, filepath.FromSlash(rel)), content, name); err != nil {
+			os.RemoveAll(dir)
+			return
+		}
+	}
+
+	for _, n := range buildNames {
+		bc := c.BuildConfigs[n]
+		bc.Packages = append(append([]Package(nil), bc.Packages...), Package{Name: name, Location: name})
+		c.BuildConfigs[n] = bc
+	}
+
+	return
+}
+
+func writeScaffoldFile(path, content, name string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0754); err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0664)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	t, err := template.New(path).Parse(content)
+	if err != nil {
+		return
+	}
+
+	return t.Execute(f, name)
+}
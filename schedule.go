@@ -0,0 +1,143 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule runs builds periodically in watch mode, whatever the changes
+type Schedule struct {
+	Cron   string   `json:"cron"`             // Cron expression "minute hour day month weekday" (local time), or @hourly, @daily, @weekly, @monthly, @yearly
+	Builds []string `json:"builds,omitempty"` // Build configs to build (optional, default the watched ones)
+	Reset  bool     `json:"reset,omitempty"`  // Empty DestDir before building
+}
+
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// cronExpr is a parsed cron expression, the allowed values of each field
+type cronExpr struct {
+	minute, hour, day, month, weekday map[int]bool
+
+	// When both day and weekday are restricted, a time matching any of
+	// them matches
+	anyDay bool
+}
+
+// parseCron parses the cron expression spec
+func parseCron(spec string) (e cronExpr, err error) {
+	if m, ok := cronMacros[spec]; ok {
+		spec = m
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return e, fmt.Errorf("Invalid cron expression '%s', 5 fields expected", spec)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := [5]*map[int]bool{&e.minute, &e.hour, &e.day, &e.month, &e.weekday}
+
+	for i, f := range fields {
+		if *sets[i], err = parseCronField(f, bounds[i][0], bounds[i][1]); err != nil {
+			return e, fmt.Errorf("Invalid cron expression '%s': %s", spec, err)
+		}
+	}
+
+	// Sunday is 0 or 7
+	if e.weekday[7] {
+		e.weekday[0] = true
+	}
+
+	e.anyDay = fields[2] != "*" && fields[4] != "*"
+
+	return
+}
+
+// parseCronField parses a comma separated list of *, n, n-m, optionally
+// followed by /step, whose values are within min and max
+func parseCronField(f string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(f, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step in '%s'", part)
+			}
+			step, part = s, part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value '%s'", part)
+			}
+
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value '%s'", part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+
+			if from < min || to > max || from > to {
+				return nil, fmt.Errorf("'%s' is out of %d-%d", part, min, max)
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (e cronExpr) matchesDay(t time.Time) bool {
+	day, weekday := e.day[t.Day()], e.weekday[int(t.Weekday())]
+	if e.anyDay {
+		return day || weekday
+	}
+
+	return day && weekday
+}
+
+// next returns the first time matching e after t, the zero time if there is
+// none within 5 years (e.g. on February 30th)
+func (e cronExpr) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !e.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !e.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !e.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !e.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
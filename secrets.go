@@ -0,0 +1,200 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// scrubMask replaces the secrets in the output and the errors
+const scrubMask = "********"
+
+// SecretProvider returns the secret of the given name, e.g. the token of a
+// deploy target, so that the credentials are never part of the config. It
+// lets a vault or a cloud secret manager supply them, see Config.Secrets.
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// EnvSecrets is the SecretProvider reading the secrets from the environment
+// variables of their name, prefixed with its value, e.g. "DEPLOY_"
+type EnvSecrets string
+
+func (p EnvSecrets) Secret(name string) (string, error) {
+	v, ok := os.LookupEnv(string(p) + name)
+	if !ok {
+		return "", fmt.Errorf("No %s%s environment variable", string(p), name)
+	}
+
+	return v, nil
+}
+
+// FileSecrets is the SecretProvider reading the secrets from the files of
+// their name in its directory, e.g. "/run/secrets" for the Docker and
+// Kubernetes secrets. The trailing line ending of the files is removed.
+type FileSecrets string
+
+func (p FileSecrets) Secret(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == ".." {
+		return "", fmt.Errorf("Invalid secret name '%s'", name)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(string(p), name))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// scrubbed holds the secrets read by the builds, removed from their output
+var scrubbed struct {
+	sync.RWMutex
+	values map[string]bool
+	r      *strings.Replacer
+}
+
+// Secret returns the secret name of the Secrets of c, EnvSecrets("") if
+// nil. Its value is then scrubbed from the output and the errors of the
+// deployments: custom Deployers should read their credentials with it.
+func (c *Config) Secret(name string) (string, error) {
+	var p SecretProvider = EnvSecrets("")
+	if c.Secrets != nil {
+		p = c.Secrets
+	}
+
+	v, err := p.Secret(name)
+	if err != nil {
+		return "", fmt.Errorf("Cannot read the secret %s: %s", name, err)
+	} else if v == "" {
+		return "", errors.New("The secret " + name + " is empty")
+	}
+
+	addScrubbed(v)
+
+	return v, nil
+}
+
+// secretValues returns the secrets of c of the names of secrets by name
+func (c *Config) secretValues(secrets map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(secrets))
+	for k, name := range secrets {
+		v, err := c.Secret(name)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+
+	return values, nil
+}
+
+// addScrubbed adds v to the scrubbed secrets
+func addScrubbed(v string) {
+	scrubbed.Lock()
+	defer scrubbed.Unlock()
+
+	if scrubbed.values[v] {
+		return
+	}
+
+	if scrubbed.values == nil {
+		scrubbed.values = make(map[string]bool)
+	}
+	scrubbed.values[v] = true
+
+	// The longest secrets first, so that the ones containing others are
+	// scrubbed entirely
+	values := make([]string, 0, len(scrubbed.values))
+	for s := range scrubbed.values {
+		values = append(values, s)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	pairs := make([]string, 0, 2*len(values))
+	for _, s := range values {
+		pairs = append(pairs, s, scrubMask)
+	}
+	scrubbed.r = strings.NewReplacer(pairs...)
+}
+
+// scrub returns s without the secrets read so far
+func scrub(s string) string {
+	scrubbed.RLock()
+	defer scrubbed.RUnlock()
+
+	if scrubbed.r == nil {
+		return s
+	}
+
+	return scrubbed.r.Replace(s)
+}
+
+// scrubbedError is an error whose message is scrubbed
+type scrubbedError struct {
+	msg string
+	err error
+}
+
+func (e *scrubbedError) Error() string { return e.msg }
+
+func (e *scrubbedError) Unwrap() error { return e.err }
+
+// scrubError returns err without the secrets in its message
+func scrubError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	if s := scrub(msg); s != msg {
+		return &scrubbedError{msg: s, err: err}
+	}
+
+	return err
+}
+
+// scrubWriter writes to w the lines written to it without the secrets. The
+// last line is written once terminated or on Close.
+type scrubWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func newScrubWriter(w io.Writer) *scrubWriter {
+	return &scrubWriter{w: w}
+}
+
+func (s *scrubWriter) Write(p []byte) (int, error) {
+	s.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(s.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		line := s.buf.Next(i + 1)
+		if _, err := io.WriteString(s.w, scrub(string(line))); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (s *scrubWriter) Close() error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(s.w, scrub(s.buf.String()))
+	s.buf.Reset()
+
+	return err
+}
@@ -0,0 +1,100 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// PackageShim is a fix for a package known to break the dojo builder. Its
+// resource tags are added to the package ones when the Detect path exists.
+type PackageShim struct {
+	Package string // Name of the package the shim applies to
+	Detect  string // Path, relative to the package location, whose existence triggers the shim
+	Reason  string
+	Tags    ResourceTags
+}
+
+// PackageShims are the shims applied to the packages of every build config
+// which does not set DisableShims
+var PackageShims = []PackageShim{
+	{
+		Package: "dojox",
+		Detect:  "gfx/silverlight.js",
+		Reason:  "the Silverlight renderer is not an AMD module",
+		Tags:    ResourceTags{CopyOnly: []RegExp{`^dojox/gfx/silverlight`}, MiniExclude: []RegExp{`^dojox/gfx/silverlight`}},
+	},
+	{
+		Package: "dojox",
+		Detect:  "storage",
+		Reason:  "the Flash and Gears storage providers use the legacy loader",
+		Tags:    ResourceTags{CopyOnly: []RegExp{`^dojox/(storage|flash|off)/`}, MiniExclude: []RegExp{`^dojox/(storage|flash|off)/`}},
+	},
+	{
+		Package: "dojox",
+		Detect:  "embed",
+		Reason:  "the Flash and Quicktime embedding modules use the legacy loader",
+		Tags:    ResourceTags{CopyOnly: []RegExp{`^dojox/(embed|av)/`}},
+	},
+	{
+		Package: "dojox",
+		Detect:  "mobile/build",
+		Reason:  "the dojox/mobile build scripts are not browser modules",
+		Tags:    ResourceTags{CopyOnly: []RegExp{`^dojox/mobile/build/`}, MiniExclude: []RegExp{`^dojox/mobile/build/`}},
+	},
+	{
+		Package: "dojox",
+		Detect:  "rpc/SMDLibrary",
+		Reason:  "the SMD files are JSON, not JavaScript",
+		Tags:    ResourceTags{CopyOnly: []RegExp{`\.smd$`}},
+	},
+	{
+		Package: "xstyle",
+		Detect:  "build",
+		Reason:  "the xstyle build plugins run in node",
+		Tags:    ResourceTags{CopyOnly: []RegExp{`^xstyle/build/`}, MiniExclude: []RegExp{`^xstyle/build/`}},
+	},
+	{
+		Package: "put-selector",
+		Detect:  "node-html.js",
+		Reason:  "the node-html module requires node modules",
+		Tags:    ResourceTags{CopyOnly: []RegExp{`^put-selector/node-html`}, MiniExclude: []RegExp{`^put-selector/node-html`}},
+	},
+}
+
+// applyPackageShims returns a copy of the packages with the resource tags of
+// the PackageShims detected in srcDir added
+func applyPackageShims(w io.Writer, srcDir string, packages []Package) []Package {
+	shimmed := make([]Package, len(packages))
+
+	for i, p := range packages {
+		for _, s := range PackageShims {
+			if s.Package != p.Name {
+				continue
+			}
+
+			if _, err := os.Stat(filepath.Join(p.Dir(srcDir), s.Detect)); err != nil {
+				continue
+			}
+
+			fmt.Fprintf(w, "Applying %s shim (%s): %s\n", p.Name, s.Detect, s.Reason)
+
+			tags := ResourceTags{}
+			if p.ResourceTags != nil {
+				tags = *p.ResourceTags
+			}
+
+			tags.AMD = append(append([]RegExp(nil), tags.AMD...), s.Tags.AMD...)
+			tags.CopyOnly = append(append([]RegExp(nil), tags.CopyOnly...), s.Tags.CopyOnly...)
+			tags.Test = append(append([]RegExp(nil), tags.Test...), s.Tags.Test...)
+			tags.MiniExclude = append(append([]RegExp(nil), tags.MiniExclude...), s.Tags.MiniExclude...)
+
+			p.ResourceTags = &tags
+		}
+
+		shimmed[i] = p
+	}
+
+	return shimmed
+}
@@ -0,0 +1,342 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	layerCacheRegexp = regexp.MustCompile(`require\(\s*\{\s*cache\s*:\s*\{`)
+	layerEntryRegexp = regexp.MustCompile(`^['"]([^'"\s]+)['"]\s*:\s*(?:function|['"])`)
+)
+
+// SizeBaseline stores the layer sizes of the last successful builds
+type SizeBaseline struct {
+	Layers map[string]LayerSizes `json:"layers"` // "config:layer" => sizes
+}
+
+// LayerSizes are the sizes of a built layer
+type LayerSizes struct {
+	Gzip    int64          `json:"gzip"`    // Gzip size of the layer
+	Modules map[string]int `json:"modules"` // Module id => size in the layer
+}
+
+type byteCounter int64
+
+func (bc *byteCounter) Write(p []byte) (int, error) {
+	*bc += byteCounter(len(p))
+	return len(p), nil
+}
+
+// gzipSize returns the size of b once gzipped
+func gzipSize(b []byte) (int64, error) {
+	var n byteCounter
+
+	w, err := gzip.NewWriterLevel(&n, gzip.BestCompression)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = w.Write(b); err != nil {
+		return 0, err
+	}
+
+	if err = w.Close(); err != nil {
+		return 0, err
+	}
+
+	return int64(n), nil
+}
+
+// layerModuleSizes returns the size of each module of the require.cache of
+// a built layer, as the distance to the next module.
+func layerModuleSizes(b []byte) map[string]int {
+	sizes := make(map[string]int)
+
+	for mid, src := range layerModuleSources(b) {
+		sizes[mid] = len(src)
+	}
+
+	return sizes
+}
+
+// layerModuleSources returns the source of each module of the require.cache
+// of a built layer, up to the next module. Only the keys of the cache map
+// are modules: the object literals of the modules sources are skipped.
+func layerModuleSources(b []byte) map[string][]byte {
+	sources := make(map[string][]byte)
+
+	loc := layerCacheRegexp.FindIndex(b)
+	if loc == nil {
+		return sources
+	}
+
+	type entry struct {
+		mid   string
+		start int
+	}
+
+	var entries []entry
+
+	// Scans the cache map, skipping the strings, comments and regexps of the
+	// sources so that their brackets do not count
+	end, depth, prev := len(b), 0, byte('{')
+	for i := loc[1]; i < len(b); i++ {
+		ch := b[i]
+
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			if depth == 0 && (prev == '{' || prev == ',') {
+				if m := layerEntryRegexp.FindSubmatch(b[i:]); m != nil {
+					entries = append(entries, entry{string(m[1]), i})
+				}
+			}
+			i = skipQuoted(b, i, ch)
+		case ch == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			continue
+		case ch == '/' && i+1 < len(b) && b[i+1] == '*':
+			if j := bytes.Index(b[i+2:], []byte("*/")); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(b)
+			}
+			continue
+		case ch == '/' && regexpAllowed(b[loc[1]:i], prev):
+			i = skipQuoted(b, i, ch)
+		case ch == '{' || ch == '(' || ch == '[':
+			depth++
+		case ch == '}' || ch == ')' || ch == ']':
+			if depth == 0 {
+				end = i
+				break
+			}
+			depth--
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			continue
+		}
+
+		if end != len(b) {
+			break
+		}
+		prev = ch
+	}
+
+	for i, e := range entries {
+		next := end
+		if i+1 < len(entries) {
+			next = entries[i+1].start
+		}
+
+		sources[e.mid] = b[e.start:next]
+	}
+
+	return sources
+}
+
+// regexpAllowed returns whether a slash following src, whose last non blank
+// character is prev, opens a regexp literal rather than being a division
+func regexpAllowed(src []byte, prev byte) bool {
+	if strings.IndexByte("(,=:[!&|?{};+-*%<>~^", prev) >= 0 {
+		return true
+	}
+
+	src = bytes.TrimRight(src, " \t\r\n")
+	w := len(src)
+	for w > 0 && (src[w-1] >= 'a' && src[w-1] <= 'z') {
+		w--
+	}
+	if w > 0 && (src[w-1] == '_' || src[w-1] == '$' || src[w-1] == '.' ||
+		(src[w-1] >= 'A' && src[w-1] <= 'Z') || (src[w-1] >= '0' && src[w-1] <= '9')) {
+		return false
+	}
+
+	switch string(src[w:]) {
+	case "return", "typeof", "case", "do", "else", "in", "of", "void", "delete", "throw", "new", "instanceof", "yield":
+		return true
+	}
+
+	return false
+}
+
+// skipQuoted returns the index of the closing quote of the string, template
+// or regexp literal of b opened by quote at i
+func skipQuoted(b []byte, i int, quote byte) int {
+	class := false
+
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '[':
+			class = quote == '/'
+		case ']':
+			class = false
+		case quote:
+			if !class {
+				return i
+			}
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+
+	return len(b)
+}
+
+// computeLayerSizes returns the sizes of the layers of r
+func computeLayerSizes(r *BuildResult) (sizes map[string]LayerSizes, err error) {
+	sizes = make(map[string]LayerSizes)
+
+	for _, l := range r.Layers {
+		if l.Err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(l.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		gz, err := gzipSize(b)
+		if err != nil {
+			return nil, err
+		}
+
+		sizes[r.Name+":"+l.Name] = LayerSizes{Gzip: gz, Modules: layerModuleSizes(b)}
+	}
+
+	return
+}
+
+func readSizeBaseline(path string) (*SizeBaseline, error) {
+	sb := &SizeBaseline{Layers: make(map[string]LayerSizes)}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return sb, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(b, sb); err != nil {
+		return nil, err
+	}
+
+	if sb.Layers == nil {
+		sb.Layers = make(map[string]LayerSizes)
+	}
+
+	return sb, nil
+}
+
+// checkLayerSizes compares the layer sizes of r with the baseline stored
+// in SizeBaseline. Layers growing more than bc.MaxLayerGrowth percent are
+// reported and fail the build if bc.FailOnLayerGrowth is set. Otherwise it
+// returns the baseline updated with the new sizes, saved by saveSizeBaseline
+// once the build passed its tests.
+func (c *Config) checkLayerSizes(bc BuildConfig, r *BuildResult) (sb *SizeBaseline, err error) {
+	if c.SizeBaseline == "" {
+		return
+	}
+
+	sb, err = readSizeBaseline(c.SizeBaseline)
+	if err != nil {
+		return
+	}
+
+	sizes, err := computeLayerSizes(r)
+	if err != nil {
+		return
+	}
+
+	var grown []string
+
+	for key, s := range sizes {
+		old, ok := sb.Layers[key]
+		if !ok || old.Gzip == 0 || bc.MaxLayerGrowth <= 0 {
+			continue
+		}
+
+		growth := float64(s.Gzip-old.Gzip) * 100 / float64(old.Gzip)
+		if growth <= bc.MaxLayerGrowth {
+			continue
+		}
+
+		fmt.Fprintf(c.stdout(), "Layer %s grew by %.1f%% (%d => %d gzipped bytes)\n", key, growth, old.Gzip, s.Gzip)
+		for _, line := range moduleGrowth(old.Modules, s.Modules) {
+			fmt.Fprintln(c.stdout(), "  "+line)
+		}
+
+		grown = append(grown, key)
+	}
+
+	if len(grown) > 0 && bc.FailOnLayerGrowth {
+		sort.Strings(grown)
+		return nil, fmt.Errorf("Layers grew more than %.1f%%: %s", bc.MaxLayerGrowth, strings.Join(grown, ", "))
+	}
+
+	for key, s := range sizes {
+		sb.Layers[key] = s
+	}
+
+	return
+}
+
+// saveSizeBaseline writes sb, if any, into SizeBaseline
+func (c *Config) saveSizeBaseline(sb *SizeBaseline) error {
+	if sb == nil {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(sb, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.SizeBaseline, b, 0664)
+}
+
+// moduleGrowth describes the modules added or grown between old and new,
+// biggest growth first.
+func moduleGrowth(old, new map[string]int) (lines []string) {
+	type delta struct {
+		mid  string
+		diff int
+		line string
+	}
+
+	var deltas []delta
+
+	for mid, size := range new {
+		if prev, ok := old[mid]; !ok {
+			deltas = append(deltas, delta{mid, size, fmt.Sprintf("+ %s (%d bytes)", mid, size)})
+		} else if size > prev {
+			deltas = append(deltas, delta{mid, size - prev, fmt.Sprintf("~ %s (+%d bytes)", mid, size-prev)})
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].diff == deltas[j].diff {
+			return deltas[i].mid < deltas[j].mid
+		}
+		return deltas[i].diff > deltas[j].diff
+	})
+
+	for _, d := range deltas {
+		lines = append(lines, d.line)
+	}
+
+	return
+}
@@ -0,0 +1,48 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const defaultNodeBin = "node"
+
+// RunSmokeTests runs the smoke tests of the build config name against the
+// release in DestDir. Each test is a node script (path relative to SrcDir)
+// executed in DestDir with the release on NODE_PATH, its path in the
+// DOJOBUILDER_RELEASE_DIR environment variable and the build id in
+// BuildIDEnv. A non-zero exit fails.
+func (c *Config) RunSmokeTests(name string) (err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return configNotFound(name)
+	}
+
+	bin := c.NodeBin
+	if bin == "" {
+		bin = defaultNodeBin
+	}
+
+	for _, t := range bc.SmokeTests {
+		script := t
+		if !filepath.IsAbs(script) {
+			script = filepath.Join(c.SrcDir, t)
+		}
+
+		cmd := exec.Command(bin, script, c.DestDir)
+		cmd.Dir = c.DestDir
+		cmd.Env = append(os.Environ(), "NODE_PATH="+c.DestDir, "DOJOBUILDER_RELEASE_DIR="+c.DestDir, BuildIDEnv+"="+c.buildID)
+		cmd.Stdout = c.stdout()
+		cmd.Stderr = c.stderr()
+
+		if err = cmd.Run(); err != nil {
+			return fmt.Errorf("Smoke test %s failed: %s", t, err)
+		}
+
+		fmt.Fprintf(c.stdout(), "Smoke test %s passed\n", t)
+	}
+
+	return
+}
@@ -0,0 +1,112 @@
+package dojoBuilder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
+)
+
+// Snapshot modes
+const (
+	SnapshotLink = "link" // Hard link the source files (fallback to copy)
+	SnapshotCopy = "copy" // Copy the source files
+)
+
+// SnapshotHashFileName is the name of the file written in DestDir containing
+// the hash of the snapshot the build was made from.
+const SnapshotHashFileName = "dojoBuilder.snapshot"
+
+// snapshot copies SrcDir into an isolated directory so the sources can't
+// change during the build. It returns a copy of c using the snapshot as
+// SrcDir and writes the snapshot hash into DestDir.
+func (c *Config) snapshot() (sc *Config, err error) {
+	if c.Snapshot != SnapshotLink && c.Snapshot != SnapshotCopy {
+		return nil, fmt.Errorf("Unknown snapshot mode '%s'", c.Snapshot)
+	}
+
+	snapshotDir := c.workPath(c.DestDir, "dojoBuilderSnapshot", "")
+	os.RemoveAll(snapshotDir)
+
+	h := sha256.New()
+
+	err = filepath.Walk(c.SrcDir, func(path string, f os.FileInfo, err error) (_err error) {
+		if err != nil {
+			return err
+		}
+
+		dest, _err := destPath(c.SrcDir, snapshotDir, path)
+		if _err != nil {
+			return
+		}
+		rel := filepath.ToSlash(dest[len(snapshotDir):])
+
+		if f.IsDir() {
+			return os.MkdirAll(dest, 0754)
+		}
+
+		if f.Mode()&os.ModeSymlink != 0 {
+			origPath, _err := filepath.EvalSymlinks(path)
+			if _err != nil {
+				return _err
+			}
+			if _err = os.Symlink(origPath, dest); _err != nil {
+				return _err
+			}
+			_, _err = io.WriteString(h, rel+"\x00"+origPath+"\x00")
+			return _err
+		}
+
+		if c.Snapshot == SnapshotCopy {
+			_err = copyutil.CopyContents(path, dest)
+		} else {
+			_err = copyutil.CopyFile(path, dest)
+		}
+
+		if _err != nil {
+			return
+		}
+
+		// Hash the snapshot and not the source, which may have changed since
+		return hashFile(h, rel, dest)
+	})
+
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	fmt.Fprintf(c.stdout(), "Building from snapshot %s\n", hash)
+
+	if err = ioutil.WriteFile(filepath.Join(c.DestDir, SnapshotHashFileName), []byte(hash+"\n"), 0664); err != nil {
+		os.RemoveAll(snapshotDir)
+		return
+	}
+
+	cc := *c
+	cc.SrcDir = snapshotDir
+
+	return &cc, nil
+}
+
+func hashFile(h io.Writer, name, path string) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if _, err = io.WriteString(h, name+"\x00"); err != nil {
+		return
+	}
+
+	_, err = io.Copy(h, f)
+
+	return
+}
@@ -0,0 +1,77 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSpaceFactor is the default ratio between the space needed by a
+// build and the size of SrcDir
+const DefaultSpaceFactor = 2.0
+
+// checkSpace fails if the file system of DestDir doesn't have enough space
+// for a build, estimated from the size of SrcDir and of the DojoDir out of
+// it.
+func (c *Config) checkSpace() (err error) {
+	factor := c.SpaceFactor
+	if factor < 0 {
+		return
+	} else if factor == 0 {
+		factor = DefaultSpaceFactor
+	}
+
+	if c.Snapshot == SnapshotCopy {
+		factor++
+	}
+
+	size, err := dirSize(c.SrcDir)
+	if err != nil {
+		return
+	}
+
+	if c.DojoDir != "" && !isSubDir(c.SrcDir, c.DojoDir) {
+		var toolkitSize int64
+		if toolkitSize, err = dirSize(c.DojoDir); err != nil {
+			return
+		}
+		size += toolkitSize
+	}
+
+	free, err := freeSpace(c.DestDir)
+	if err != nil {
+		return
+	}
+
+	needed := uint64(float64(size) * factor)
+	if needed > free {
+		return fmt.Errorf("Not enough space in %s: %d MB needed, %d MB available", c.DestDir, needed>>20, free>>20)
+	}
+
+	return
+}
+
+// isSubDir reports whether dir is parent or one of its subdirectories
+func isSubDir(parent, dir string) bool {
+	rel, err := filepath.Rel(parent, dir)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// dirSize returns the total size of the regular files of dir
+func dirSize(dir string) (size int64, err error) {
+	err = filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if f.Mode().IsRegular() {
+			size += f.Size()
+		}
+
+		return nil
+	})
+
+	return
+}
@@ -0,0 +1,153 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SplitSuggestionMinShare is the minimum share (percent of the layer gzip
+// size) of a module for LayerSplitSuggestions to suggest moving it out
+var SplitSuggestionMinShare = 5.0
+
+var (
+	moduleDepsRegexp = regexp.MustCompile(`define\(\s*(?:['"][^'"]*['"]\s*,\s*)?\[([^\]]*)\]`)
+	stringRegexp     = regexp.MustCompile(`['"]([^'"]+)['"]`)
+)
+
+// LayerSplitSuggestion suggests moving a module out of a boot layer into a
+// layer loaded on demand
+type LayerSplitSuggestion struct {
+	Layer      string   // Boot layer module id
+	Module     string   // Module id
+	Gzip       int64    // Gzip size of the module
+	Share      float64  // Percent of the layer gzip size
+	Dependents []string // Modules of the layer depending on Module
+}
+
+func (s LayerSplitSuggestion) String() string {
+	by := "no module of the layer"
+	if len(s.Dependents) > 0 {
+		by = strings.Join(s.Dependents, ", ")
+	}
+
+	return fmt.Sprintf("%s: move %s (%d bytes gzipped, %.1f%%, required by %s) to a lazy layer",
+		s.Layer, s.Module, s.Gzip, s.Share, by)
+}
+
+// LayerSplitSuggestions analyses the boot layers of the build config name
+// output in DestDir and suggests moving out the heavy modules (at least
+// SplitSuggestionMinShare of the layer gzip size) which are not included
+// explicitly and which at most one module of the layer depends on. The
+// suggestions are advisory only, the modules may still be needed at startup.
+func (c *Config) LayerSplitSuggestions(name string) (suggestions []LayerSplitSuggestion, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return nil, configNotFound(name)
+	}
+
+	for mid, l := range bc.Layers {
+		if !l.Boot {
+			continue
+		}
+
+		p, err := c.builtLayer(name, bc, mid)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		total, err := gzipSize(b)
+		if err != nil || total == 0 {
+			return nil, err
+		}
+
+		included := map[string]bool{mid: true}
+		for _, i := range l.Include {
+			included[i] = true
+		}
+
+		sources := layerModuleSources(b)
+
+		dependents := make(map[string][]string)
+		for m, src := range sources {
+			for _, d := range moduleDependencies(m, src) {
+				dependents[d] = append(dependents[d], m)
+			}
+		}
+
+		for m, src := range sources {
+			if included[m] || strings.HasPrefix(m, "url:") || len(dependents[m]) > 1 {
+				continue
+			}
+
+			gz, err := gzipSize(src)
+			if err != nil {
+				return nil, err
+			}
+
+			share := float64(gz) * 100 / float64(total)
+			if share < SplitSuggestionMinShare {
+				continue
+			}
+
+			sort.Strings(dependents[m])
+			suggestions = append(suggestions, LayerSplitSuggestion{
+				Layer:      mid,
+				Module:     m,
+				Gzip:       gz,
+				Share:      share,
+				Dependents: dependents[m],
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool { return suggestions[i].Gzip > suggestions[j].Gzip })
+
+	return
+}
+
+// moduleDependencies returns the absolute module ids of the dependencies of
+// the define() call of src, the module mid, without their plugin resources
+func moduleDependencies(mid string, src []byte) (deps []string) {
+	m := moduleDepsRegexp.FindSubmatch(src)
+	if m == nil {
+		return
+	}
+
+	for _, s := range stringRegexp.FindAllSubmatch(m[1], -1) {
+		d := string(s[1])
+		if i := strings.IndexByte(d, '!'); i >= 0 {
+			d = d[:i]
+		}
+
+		if strings.HasPrefix(d, ".") {
+			d = path.Join(path.Dir(mid), d)
+		}
+
+		deps = append(deps, d)
+	}
+
+	return
+}
+
+// PrintLayerSplitSuggestions writes the suggestions to w
+func PrintLayerSplitSuggestions(w io.Writer, suggestions []LayerSplitSuggestion) {
+	if len(suggestions) == 0 {
+		fmt.Fprintln(w, "No layer split suggestion")
+		return
+	}
+
+	fmt.Fprintln(w, "Layer split suggestions:")
+	for _, s := range suggestions {
+		fmt.Fprintln(w, "  "+s.String())
+	}
+}
@@ -0,0 +1,84 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Statuses of a build in a summary
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // Not built because a previous build failed
+)
+
+// BuildSummaryRow sums up the build of a build config
+type BuildSummaryRow struct {
+	Name     string
+	Status   string // StatusOK, StatusFailed or StatusSkipped
+	Duration time.Duration
+	Warnings int
+	Errors   int
+	Size     int64  // Total size of the layers output
+	Dest     string // Directory the release was copied into
+}
+
+// BuildSummary returns a row for each build config of names, from their
+// results. The build configs without result are skipped.
+func BuildSummary(names []string, results []*BuildResult) []BuildSummaryRow {
+	byName := make(map[string]*BuildResult, len(results))
+	for _, r := range results {
+		byName[r.Name] = r
+	}
+
+	rows := make([]BuildSummaryRow, 0, len(names))
+
+	for _, n := range names {
+		r, ok := byName[n]
+		if !ok {
+			rows = append(rows, BuildSummaryRow{Name: n, Status: StatusSkipped})
+			continue
+		}
+
+		row := BuildSummaryRow{
+			Name:     n,
+			Status:   StatusOK,
+			Duration: r.Duration,
+			Warnings: r.Warnings,
+			Errors:   r.Errors,
+			Dest:     r.Dest,
+		}
+
+		if r.Err != nil {
+			row.Status = StatusFailed
+		}
+
+		for _, l := range r.Layers {
+			row.Size += l.Size
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// PrintBuildSummary writes rows to w as a table
+func PrintBuildSummary(w io.Writer, rows []BuildSummaryRow) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "BUILD\tSTATUS\tDURATION\tWARNINGS\tERRORS\tLAYERS SIZE\tDESTINATION")
+	for _, r := range rows {
+		if r.Status == StatusSkipped {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t-\n", r.Name, r.Status)
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%.1f kB\t%s\n", r.Name, r.Status,
+			r.Duration.Round(time.Millisecond), r.Warnings, r.Errors, float64(r.Size)/1000, r.Dest)
+	}
+
+	tw.Flush()
+}
@@ -0,0 +1,117 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// Test runners supported by TestSuite
+const (
+	InternTestRunner = "intern"
+	DOHTestRunner    = "doh"
+)
+
+// TestSuite describes the test suite of a build config
+type TestSuite struct {
+	Runner  string // InternTestRunner or DOHTestRunner
+	Module  string // Intern config module id, or DOH test module id
+	Release bool   // Test the release in DestDir instead of the sources in SrcDir, the runner is still the one of SrcDir
+}
+
+// TestResult is the result of a test suite run
+type TestResult struct {
+	Tests    int
+	Failures int
+}
+
+var (
+	// Intern: "TOTAL: tested 1 platforms, 2/10 tests failed"
+	internSummaryRegexp = regexp.MustCompile(`(\d+)/(\d+) tests? failed`)
+	// DOH: "	10 tests in 2 groups", "	1 errors", "	1 failures"
+	dohTestsRegexp    = regexp.MustCompile(`^\s*(\d+) tests in \d+ groups`)
+	dohFailuresRegexp = regexp.MustCompile(`^\s*(\d+) (errors|failures)`)
+)
+
+// RunTests runs the test suite of the build config name with node and
+// returns its result. An error is returned if the suite cannot be run or
+// if any test fails.
+func (c *Config) RunTests(name string) (tr TestResult, err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return tr, configNotFound(name)
+	}
+
+	ts := bc.Tests
+	if ts == nil {
+		return tr, errors.New("No test suite defined for build config '" + name + "'")
+	}
+
+	dir, packages := c.SrcDir, c.sourcePackages(bc.Packages)
+	if ts.Release {
+		dir, packages = c.DestDir, releasePackages(bc.Packages)
+	}
+
+	var args []string
+	switch ts.Runner {
+	case InternTestRunner:
+		// node_modules is not part of the release
+		args = []string{filepath.Join(c.SrcDir, "node_modules", "intern", "client.js"), "config=" + ts.Module}
+	case DOHTestRunner:
+		dojo, perr := NewResolver(dir, packages, nil).Path("dojo/dojo")
+		if perr != nil {
+			return tr, perr
+		}
+		args = []string{dojo, "load=doh", "test=" + ts.Module}
+	default:
+		return tr, fmt.Errorf("Unknown test runner '%s'", ts.Runner)
+	}
+
+	bin := c.NodeBin
+	if bin == "" {
+		bin = defaultNodeBin
+	}
+
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), BuildIDEnv+"="+c.buildID)
+	cmd.Stderr = c.stderr()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+
+	if err = cmd.Start(); err != nil {
+		return
+	}
+
+	rerr := readLines(stdout, func(line string) {
+		fmt.Fprintln(c.stdout(), line)
+
+		if m := internSummaryRegexp.FindStringSubmatch(line); m != nil {
+			tr.Failures, _ = strconv.Atoi(m[1])
+			tr.Tests, _ = strconv.Atoi(m[2])
+		} else if m := dohTestsRegexp.FindStringSubmatch(line); m != nil {
+			tr.Tests, _ = strconv.Atoi(m[1])
+		} else if m := dohFailuresRegexp.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			tr.Failures += n
+		}
+	})
+
+	if err = cmd.Wait(); err != nil {
+		return tr, fmt.Errorf("Tests of %s build failed: %s", name, err)
+	} else if rerr != nil {
+		return tr, fmt.Errorf("Cannot read the test output: %s", rerr)
+	}
+
+	if tr.Failures > 0 {
+		return tr, fmt.Errorf("%d/%d tests of %s build failed", tr.Failures, tr.Tests, name)
+	}
+
+	return
+}
@@ -0,0 +1,265 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
+)
+
+const defaultLessBin = "lessc"
+
+// Theme is a dijit theme (e.g. dijit/themes/claro or a copy of it) built
+// into its own output directory
+type Theme struct {
+	Name      string            // Theme name, the main stylesheet is Name.css
+	Src       string            // Directory of the theme sources, relative to SrcDir
+	Dest      string            // Output directory, relative to DestDir (optional, default "themes/" + Name)
+	Variables map[string]string // LESS variables overriding the ones of the theme variables.less, e.g. "primary-color": "#cf3a12"
+	LessBin   string            // Path of the LESS compiler (optional, default "lessc")
+}
+
+var (
+	cssCommentRegexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssImportRegexp  = regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'")\s]+)['"]?\s*\)?[^;]*;`)
+	cssURLRegexp     = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+)
+
+// BuildTheme builds the theme t of the build config name: its LESS variables
+// are overridden, every stylesheet is compiled with the LESS compiler, the
+// CSS is optimized according to the CssOptimize of the build config and the
+// result is output with its manifest into the theme directory in DestDir.
+func (c *Config) BuildTheme(name string, t Theme) (err error) {
+	bc, ok := c.BuildConfigs[name]
+	if !ok {
+		return configNotFound(name)
+	}
+
+	if t.Name == "" || t.Src == "" {
+		return errors.New("A theme needs a name and a source directory")
+	}
+
+	fmt.Fprintf(c.stdout(), "Building %s theme\n", t.Name)
+
+	src := filepath.Join(c.SrcDir, t.Src)
+	tmpDir := c.workPath(c.DestDir, "dojoBuilderTHEME", name+"-"+t.Name)
+	os.RemoveAll(tmpDir)
+	defer os.RemoveAll(tmpDir)
+
+	// Files are copied, not linked, as the copies are modified
+	err = filepath.Walk(src, func(p string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		dest, err := destPath(src, tmpDir, p)
+		if err != nil {
+			return err
+		}
+		if f.IsDir() {
+			return os.MkdirAll(dest, 0754)
+		}
+
+		return copyutil.CopyContents(p, dest)
+	})
+
+	if err != nil {
+		return
+	}
+
+	if err = overrideLessVariables(filepath.Join(tmpDir, "variables.less"), t.Variables); err != nil {
+		return
+	}
+
+	if err = t.compileLess(tmpDir); err != nil {
+		return
+	}
+
+	if bc.CssOptimize != "" {
+		if err = optimizeThemeCSS(tmpDir, t.Name+".css", bc.CssOptimize); err != nil {
+			return
+		}
+	}
+
+	if bc.InlineResourcesMaxSize > 0 {
+		if err = inlineDirResources(tmpDir, bc.InlineResourcesMaxSize); err != nil {
+			return
+		}
+	}
+
+	m, err := NewManifest(tmpDir)
+	if err != nil {
+		return
+	}
+
+	if err = m.Write(filepath.Join(tmpDir, ManifestFileName)); err != nil {
+		return
+	}
+
+	dest := t.Dest
+	if dest == "" {
+		dest = "themes/" + t.Name
+	}
+	dest = filepath.Join(c.DestDir, dest)
+
+	if err = os.MkdirAll(filepath.Dir(dest), 0754); err != nil {
+		return
+	}
+
+	os.RemoveAll(dest)
+
+	return os.Rename(tmpDir, dest)
+}
+
+// overrideLessVariables appends the variables to the LESS file at p, the
+// last definition of a LESS variable being the one used
+func overrideLessVariables(p string, variables map[string]string) error {
+	if len(variables) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(variables))
+	for n := range variables {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	s := "\n/* Variables overridden by dojoBuilder */\n"
+	for _, n := range names {
+		s += fmt.Sprintf("@%s: %s;\n", strings.TrimPrefix(n, "@"), variables[n])
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0664)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.WriteString(s); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// compileLess compiles every LESS stylesheet of dir, but the variables, into
+// the CSS file of the same name
+func (t Theme) compileLess(dir string) error {
+	bin := t.LessBin
+	if bin == "" {
+		bin = defaultLessBin
+	}
+
+	return filepath.Walk(dir, func(p string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() || filepath.Ext(p) != ".less" || f.Name() == "variables.less" {
+			return err
+		}
+
+		// Partials are only meant to be imported
+		if strings.HasPrefix(f.Name(), "_") {
+			return nil
+		}
+
+		out, err := exec.Command(bin, p, strings.TrimSuffix(p, ".less")+".css").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Cannot compile %s: %s\n%s", p, err, out)
+		}
+
+		return nil
+	})
+}
+
+// optimizeThemeCSS flattens the imports of the stylesheet main of dir and
+// strips its comments the way the dojo builder cssOptimize option does
+// ("comments" or "comments.keepLines")
+func optimizeThemeCSS(dir, main, cssOptimize string) error {
+	p := filepath.Join(dir, main)
+
+	css, err := flattenCSS(dir, main, map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	css = cssCommentRegexp.ReplaceAllString(css, "")
+
+	if cssOptimize != "comments.keepLines" {
+		var lines []string
+		for _, l := range strings.Split(css, "\n") {
+			if l = strings.TrimSpace(l); l != "" {
+				lines = append(lines, l)
+			}
+		}
+		css = strings.Join(lines, "\n") + "\n"
+	}
+
+	return ioutil.WriteFile(p, []byte(css), 0664)
+}
+
+// flattenCSS returns the stylesheet rel (relative to dir) with its imports
+// inlined and their relative urls rewritten to be relative to dir
+func flattenCSS(dir, rel string, seen map[string]bool) (string, error) {
+	if seen[rel] {
+		return "", nil
+	}
+	seen[rel] = true
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", err
+	}
+
+	base := path.Dir(rel)
+	css := string(b)
+
+	var out strings.Builder
+	last := 0
+
+	for _, m := range cssImportRegexp.FindAllStringSubmatchIndex(css, -1) {
+		out.WriteString(rebaseCSSURLs(css[last:m[0]], base))
+		last = m[1]
+
+		target := css[m[2]:m[3]]
+		if isAbsoluteURL(target) {
+			out.WriteString(css[m[0]:m[1]])
+			continue
+		}
+
+		inlined, err := flattenCSS(dir, path.Join(base, target), seen)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(inlined)
+	}
+
+	out.WriteString(rebaseCSSURLs(css[last:], base))
+
+	return out.String(), nil
+}
+
+// rebaseCSSURLs prefixes the relative urls of css with base
+func rebaseCSSURLs(css, base string) string {
+	if base == "." {
+		return css
+	}
+
+	return cssURLRegexp.ReplaceAllStringFunc(css, func(u string) string {
+		m := cssURLRegexp.FindStringSubmatch(u)
+		if isAbsoluteURL(m[2]) {
+			return u
+		}
+
+		return "url(" + m[1] + path.Join(base, m[2]) + m[3] + ")"
+	})
+}
+
+func isAbsoluteURL(u string) bool {
+	return strings.HasPrefix(u, "/") || strings.HasPrefix(u, "data:") || strings.Contains(u, "://")
+}
@@ -0,0 +1,153 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var kernelVersionRegexp = regexp.MustCompile(`major:\s*(\d+),\s*minor:\s*(\d+),\s*patch:\s*(\d+)`)
+
+// ToolkitVersion is the version of a dojo checkout
+type ToolkitVersion struct {
+	Major, Minor, Patch int
+}
+
+func (v ToolkitVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast returns true if v is greater or equal to major.minor
+func (v ToolkitVersion) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// ToolkitDir returns the directory of the dojo, dijit, dojox and util
+// checkouts: DojoDir, or SrcDir if it is empty
+func (c *Config) ToolkitDir() string {
+	if c.DojoDir != "" {
+		return c.DojoDir
+	}
+
+	return c.SrcDir
+}
+
+// sourcePackages returns a copy of the packages whose toolkit ones, when
+// the toolkit lives in DojoDir, are located there. They keep their location
+// in the release.
+func (c *Config) sourcePackages(packages []Package) []Package {
+	if c.DojoDir == "" {
+		return packages
+	}
+
+	toolkit := make(map[string]bool, len(ToolkitDirs))
+	for _, d := range ToolkitDirs {
+		toolkit[d] = true
+	}
+
+	sp := make([]Package, len(packages))
+	for i, p := range packages {
+		if toolkit[p.Name] && !p.External() {
+			p.DestLocation = p.ReleaseLocation()
+			p.Location = filepath.Join(c.DojoDir, p.Location)
+		}
+		sp[i] = p
+	}
+
+	return sp
+}
+
+// DojoVersion reads the version of the dojo checkout of ToolkitDir from
+// dojo/package.json, or dojo/_base/kernel.js if there is no package.json.
+func (c *Config) DojoVersion() (v ToolkitVersion, err error) {
+	dojoDir := filepath.Join(c.ToolkitDir(), "dojo")
+
+	if b, err := ioutil.ReadFile(filepath.Join(dojoDir, "package.json")); err == nil {
+		var pkg struct {
+			Version string `json:"version"`
+		}
+
+		if err = json.Unmarshal(b, &pkg); err != nil {
+			return v, err
+		}
+
+		return parseToolkitVersion(pkg.Version)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dojoDir, "_base", "kernel.js"))
+	if err != nil {
+		return v, fmt.Errorf("Cannot find dojo version in %s", dojoDir)
+	}
+
+	m := kernelVersionRegexp.FindSubmatch(b)
+	if m == nil {
+		return v, fmt.Errorf("Cannot find dojo version in %s", dojoDir)
+	}
+
+	v.Major, _ = strconv.Atoi(string(m[1]))
+	v.Minor, _ = strconv.Atoi(string(m[2]))
+	v.Patch, _ = strconv.Atoi(string(m[3]))
+
+	return
+}
+
+func parseToolkitVersion(s string) (v ToolkitVersion, err error) {
+	// Strip flags like "-dev"
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return v, fmt.Errorf("Invalid dojo version '%s'", s)
+	}
+
+	nums := make([]int, 3)
+	for i := 0; i < len(parts) && i < 3; i++ {
+		digits := parts[i]
+		if j := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); j >= 0 {
+			digits = digits[:j]
+		}
+		if nums[i], err = strconv.Atoi(digits); err != nil {
+			return v, fmt.Errorf("Invalid dojo version '%s'", s)
+		}
+	}
+
+	return ToolkitVersion{nums[0], nums[1], nums[2]}, nil
+}
+
+// applyToolkitCapabilities disables the options of bc the toolkit version v
+// doesn't support, returning a warning for each of them.
+func applyToolkitCapabilities(bc *BuildConfig, v ToolkitVersion) (warnings []string) {
+	if !v.AtLeast(1, 10) {
+		if bc.UseSourceMaps {
+			warnings = append(warnings, fmt.Sprintf("source maps are not supported by dojo %s, disabling them", v))
+			bc.UseSourceMaps = false
+		}
+
+		if strings.HasPrefix(bc.LayerOptimize, "uglify") {
+			warnings = append(warnings, fmt.Sprintf("layerOptimize %s is not supported by dojo %s, using closure", bc.LayerOptimize, v))
+			bc.LayerOptimize = "closure"
+		}
+
+		if strings.HasPrefix(bc.Optimize, "uglify") {
+			warnings = append(warnings, fmt.Sprintf("optimize %s is not supported by dojo %s, using closure", bc.Optimize, v))
+			bc.Optimize = "closure"
+		}
+	}
+
+	if v.AtLeast(1, 7) && bc.Loader == XDomainLoader {
+		warnings = append(warnings, fmt.Sprintf("the xdomain loader is not supported by dojo %s, its AMD modules load cross-domain as they are", v))
+		bc.Loader, bc.XdDojoPath = "", ""
+	}
+
+	if !v.AtLeast(1, 8) && bc.SelectorEngine != "" {
+		warnings = append(warnings, fmt.Sprintf("selectorEngine is not supported by dojo %s", v))
+	}
+
+	return
+}
@@ -0,0 +1,159 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// toolkitChecksums returns the sha256 of the files of the ToolkitDirs of
+// ToolkitDir by slash separated path relative to ToolkitDir. The git
+// metadata of the checkouts is skipped.
+func (c *Config) toolkitChecksums() (map[string][]byte, error) {
+	sums := make(map[string][]byte)
+
+	for _, d := range ToolkitDirs {
+		dir := filepath.Join(c.ToolkitDir(), d)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+
+		dirSums, err := treeChecksums(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for p, sum := range dirSums {
+			p = d + "/" + filepath.ToSlash(p)
+			if !strings.Contains("/"+p+"/", "/.git/") {
+				sums[p] = sum
+			}
+		}
+	}
+
+	return sums, nil
+}
+
+// WriteToolkitChecksums records the checksums of the dojo checkout of
+// ToolkitDir into the file at path, in the sha256sum format.
+func (c *Config) WriteToolkitChecksums(path string) error {
+	sums, err := c.toolkitChecksums()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%x  %s\n", sums[p], p)
+	}
+
+	return ioutil.WriteFile(path, b.Bytes(), 0664)
+}
+
+func readToolkitChecksums(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := make(map[string][]byte)
+
+	err = readLines(f, func(line string) {
+		if err != nil || strings.TrimSpace(line) == "" {
+			return
+		}
+
+		fields := strings.SplitN(line, "  ", 2)
+		if len(fields) != 2 {
+			err = fmt.Errorf("Invalid checksum line '%s' in %s", line, path)
+			return
+		}
+
+		sum, herr := hex.DecodeString(fields[0])
+		if herr != nil {
+			err = fmt.Errorf("Invalid checksum line '%s' in %s", line, path)
+			return
+		}
+
+		sums[fields[1]] = sum
+	})
+
+	return sums, err
+}
+
+// VerifyToolkit compares the dojo checkout of ToolkitDir with the checksums
+// recorded in the file ToolkitChecksums by WriteToolkitChecksums. It returns
+// the files modified, missing or added, which is empty if the checkout is
+// untouched.
+func (c *Config) VerifyToolkit() (diffs []string, err error) {
+	if c.ToolkitChecksums == "" {
+		return nil, errors.New("No ToolkitChecksums defined in config")
+	}
+
+	recorded, err := readToolkitChecksums(c.ToolkitChecksums)
+	if err != nil {
+		return
+	}
+
+	current, err := c.toolkitChecksums()
+	if err != nil {
+		return
+	}
+
+	for p, sum := range recorded {
+		if other, ok := current[p]; !ok {
+			diffs = append(diffs, "Missing: "+p)
+		} else if !bytes.Equal(sum, other) {
+			diffs = append(diffs, "Modified: "+p)
+		}
+	}
+
+	for p := range current {
+		if _, ok := recorded[p]; !ok {
+			diffs = append(diffs, "Added: "+p)
+		}
+	}
+
+	sort.Strings(diffs)
+
+	return
+}
+
+// toolkitDiffsShown is the number of differences listed by the error of a
+// modified toolkit
+const toolkitDiffsShown = 10
+
+// verifyToolkit fails with ErrToolkitModified if ToolkitChecksums is set
+// and the dojo checkout differs from it
+func (c *Config) verifyToolkit() error {
+	if c.ToolkitChecksums == "" {
+		return nil
+	}
+
+	diffs, err := c.VerifyToolkit()
+	if err != nil {
+		return err
+	} else if len(diffs) == 0 {
+		return nil
+	}
+
+	shown := diffs
+	if len(shown) > toolkitDiffsShown {
+		shown = append(shown[:toolkitDiffsShown:toolkitDiffsShown], fmt.Sprintf("and %d more", len(diffs)-toolkitDiffsShown))
+	}
+
+	return fmt.Errorf("%w (%s):\n\t%s", ErrToolkitModified, c.ToolkitDir(), strings.Join(shown, "\n\t"))
+}
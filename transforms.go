@@ -0,0 +1,97 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Gates of the dojo builder a transform can be attached to
+var TransformGates = []string{"read", "text", "tokenize", "tokens", "parse", "ast", "optimize", "write", "cleanup", "report"}
+
+// Transform is a custom build transform, registered in the profile's
+// transforms section as [Module, Gate]. The transforms are run on every AMD
+// module, see transformJobs, and skip the resources they do not change.
+type Transform struct {
+	Module string // Module id of the transform, e.g. "app/build/copyrightTransform"
+	Gate   string // Gate the transform is applied at, one of TransformGates
+}
+
+func (t Transform) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{t.Module, t.Gate})
+}
+
+func (t *Transform) UnmarshalJSON(b []byte) error {
+	var v []string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	} else if len(v) != 2 {
+		return fmt.Errorf("Invalid transform %s", b)
+	}
+
+	t.Module, t.Gate = v[0], v[1]
+
+	return nil
+}
+
+// amdTransforms are the transforms the default jobs of the dojo builder run
+// on the AMD modules
+var amdTransforms = []string{"read", "dojoPragmas", "hasFindAll", "insertSymbols", "hasFixup", "depsScan", "writeAmd", "writeOptimized"}
+
+// amdJobPredicate is the predicate of the job of the AMD modules, registering
+// them like the default one does
+const amdJobPredicate = `function(resource, bc){
+	if(resource.tag.amd || (/\.js$/.test(resource.src) && resource.mid && !resource.tag.copyOnly && !resource.tag.test && !resource.tag.synthetic && !/\/nls\//.test(resource.mid))){
+		bc.amdResources[resource.mid] = resource;
+		return true;
+	}
+	return false;
+}`
+
+// transformJobs returns the transformJobs of the profile of bc running its
+// transforms after the default ones on the AMD modules, nil if it has none.
+// The jobs of a profile are checked before the default ones.
+func transformJobs(bc BuildConfig) []interface{} {
+	if len(bc.Transforms) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(bc.Transforms))
+	for name := range bc.Transforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	transforms := append(append([]string(nil), amdTransforms...), names...)
+
+	return []interface{}{[]interface{}{JS(amdJobPredicate), transforms}}
+}
+
+// validateTransforms checks every transform of bc has a module and a known gate
+func validateTransforms(bc BuildConfig) error {
+	for name, t := range bc.Transforms {
+		if t.Module == "" {
+			return fmt.Errorf("Transform '%s' has no module", name)
+		}
+
+		known := false
+		for _, g := range TransformGates {
+			if t.Gate == g {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			return fmt.Errorf("Transform '%s' uses unknown gate '%s'", name, t.Gate)
+		}
+	}
+
+	for plugin, resolver := range bc.Plugins {
+		if resolver == "" {
+			return fmt.Errorf("Plugin '%s' has no build-time resolver", plugin)
+		}
+	}
+
+	return nil
+}
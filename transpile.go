@@ -0,0 +1,194 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
+)
+
+// Transpiler describes an external command (babel, esbuild...) run over some
+// packages before the dojo build so modern syntax doesn't break the optimizer.
+// In Args, {src} and {dest} are replaced by the package source and shadow
+// directories. If Args is empty, the babel CLI syntax "{src} --out-dir {dest}"
+// is used.
+type Transpiler struct {
+	Command  string   // Transpiler executable
+	Args     []string // Arguments passed to Command for each package
+	Packages []string // Names of the packages to transpile
+}
+
+var defaultTranspilerArgs = []string{"{src}", "--out-dir", "{dest}"}
+
+// transpile creates a shadow copy of SrcDir in which the packages of bc
+// selected by t are replaced by their transpiled version. It returns a copy
+// of c whose SrcDir is the shadow directory.
+func (c *Config) transpile(name string, bc BuildConfig, t *Transpiler) (tc *Config, err error) {
+	if t.Command == "" {
+		return nil, fmt.Errorf("No transpiler command defined")
+	}
+
+	shadowDir := c.workPath(c.DestDir, "dojoBuilderSRC", name)
+
+	locations := make(map[string]string)
+
+	// External packages are copied into the shadow directory as they are
+	// not walked with SrcDir
+	externals := make(map[string]string)
+	for _, n := range t.Packages {
+		found := false
+		for _, p := range bc.Packages {
+			if p.Name == n {
+				if p.External() {
+					locations[p.Dir(c.SrcDir)] = externalLocation(p)
+					externals[p.Name] = p.Dir(c.SrcDir)
+				} else {
+					locations[p.Dir(c.SrcDir)] = p.Location
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Cannot transpile unknown package '%s'", n)
+		}
+	}
+
+	os.RemoveAll(shadowDir)
+
+	err = filepath.Walk(c.SrcDir, func(path string, f os.FileInfo, err error) (_err error) {
+		if err != nil {
+			return err
+		}
+
+		dest, _err := destPath(c.SrcDir, shadowDir, path)
+		if _err != nil {
+			return
+		}
+
+		if f.IsDir() {
+			if _, ok := locations[path]; ok {
+				if _err = copyDirWithoutJS(path, dest); _err != nil {
+					return
+				}
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(dest, 0754)
+		}
+
+		return copyutil.CopyFile(path, dest)
+	})
+
+	if err != nil {
+		os.RemoveAll(shadowDir)
+		return
+	}
+
+	for _, src := range externals {
+		if err = copyDirWithoutJS(src, filepath.Join(shadowDir, locations[src])); err != nil {
+			os.RemoveAll(shadowDir)
+			return
+		}
+	}
+
+	for src, location := range locations {
+		if err = c.runTranspiler(t, src, filepath.Join(shadowDir, location)); err != nil {
+			os.RemoveAll(shadowDir)
+			return
+		}
+	}
+
+	cc := *c
+	cc.SrcDir = shadowDir
+
+	if len(externals) > 0 {
+		tbc := bc
+		tbc.Packages = make([]Package, len(bc.Packages))
+		for i, p := range bc.Packages {
+			if _, ok := externals[p.Name]; ok {
+				location := externalLocation(p)
+				p.DestLocation = p.ReleaseLocation()
+				p.Location = location
+			}
+			tbc.Packages[i] = p
+		}
+
+		cc.BuildConfigs = make(map[string]BuildConfig, len(c.BuildConfigs))
+		for n, b := range c.BuildConfigs {
+			cc.BuildConfigs[n] = b
+		}
+		cc.BuildConfigs[name] = tbc
+	}
+
+	return &cc, nil
+}
+
+// externalLocation returns the location of the transpiled copy of the
+// external package p in the shadow directory
+func externalLocation(p Package) string {
+	return "dojoBuilderExternal/" + p.Name
+}
+
+// copyDirWithoutJS copies the content of every non js file of src into dest.
+// Contents are copied rather than linked so the transpiler never writes
+// through a hard link into the original sources.
+func copyDirWithoutJS(src, dest string) error {
+	return filepath.Walk(src, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target, err := destPath(src, dest, path)
+		if err != nil {
+			return err
+		}
+
+		if f.IsDir() {
+			return os.MkdirAll(target, 0754)
+		} else if filepath.Ext(path) == ".js" {
+			return nil
+		}
+
+		return copyutil.CopyContents(path, target)
+	})
+}
+
+// runTranspiler runs the command of t transpiling the package src into dest
+func (c *Config) runTranspiler(t *Transpiler, src, dest string) (err error) {
+	args := t.Args
+	if len(args) == 0 {
+		args = defaultTranspilerArgs
+	}
+
+	r := strings.NewReplacer("{src}", src, "{dest}", dest)
+
+	cmdArgs := make([]string, len(args))
+	for i, a := range args {
+		cmdArgs[i] = r.Replace(a)
+	}
+
+	fmt.Fprintf(c.stdout(), "Transpiling %s\n", src)
+
+	cmd := c.command(t.Command, cmdArgs...)
+	cmd.Stdout = c.stdout()
+	cmd.Stderr = c.stderr()
+
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("Transpile command failed for %s: %w", src, err)
+	}
+
+	done := make(chan struct{})
+	c.killOnCancel(cmd, done)
+	err = cmd.Wait()
+	close(done)
+
+	if c.canceled() {
+		return ErrBuildCanceled
+	} else if err != nil {
+		return fmt.Errorf("Transpile command failed for %s: %w", src, err)
+	}
+
+	return
+}
@@ -0,0 +1,227 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UploadDeployer deploys by uploading every file of the release with a PUT
+// request to BaseURL followed by its path, e.g. to a WebDAV server or a
+// bucket behind a signing proxy. The files are sent with the Content-Type
+// and Cache-Control of the ContentTypes of the build config, the
+// bookkeeping files of dojoBuilder being left out. It goes on after the
+// failed uploads, which are reported by an ErrDeployPartial, and stops once
+// the Timeout of its target is exceeded, the remaining files being skipped.
+type UploadDeployer struct {
+	BaseURL       string            // Url the paths of the files, relative to DestDir, are appended to
+	HeaderSecrets map[string]string // Request header => name of the secret it is set to, e.g. "Authorization" (optional)
+	RateLimit     int64             // Max upload rate in bytes/s (optional)
+}
+
+func (d UploadDeployer) Deploy(c *Config, name string) error {
+	return d.DeployContext(context.Background(), c, name)
+}
+
+func (d UploadDeployer) DeployContext(ctx context.Context, c *Config, name string) error {
+	headers, err := c.secretValues(d.HeaderSecrets)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.httpClient(0)
+	if err != nil {
+		return err
+	}
+
+	root, err := c.ReleaseRoot(name)
+	if err != nil {
+		return err
+	}
+	bc := c.BuildConfigs[name]
+
+	var files []string
+	err = filepath.Walk(filepath.Join(c.DestDir, root), func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.Mode().IsRegular() && !isBookkeepingFile(c.DestDir, path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	limiter := newRateLimiter(d.RateLimit)
+	pe := &ErrDeployPartial{Total: len(files), Failed: make(map[string]error)}
+	baseURL := strings.TrimRight(d.BaseURL, "/")
+
+	for i, path := range files {
+		rel, err := filepath.Rel(c.DestDir, path)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			for _, p := range files[i:] {
+				if rel, err = filepath.Rel(c.DestDir, p); err == nil {
+					pe.Skipped = append(pe.Skipped, filepath.ToSlash(rel))
+				}
+			}
+			break
+		}
+
+		rel = filepath.ToSlash(rel)
+		if err = d.upload(ctx, client, limiter, headers, bc.ContentTypes.lookup(rel), baseURL+"/"+urlPath(rel), path); err != nil {
+			pe.Failed[rel] = err
+		}
+	}
+
+	if len(pe.Failed) > 0 || len(pe.Skipped) > 0 {
+		return pe
+	}
+
+	return nil
+}
+
+// upload PUTs the file at path, of the content type ct, to u
+func (d UploadDeployer) upload(ctx context.Context, client *http.Client, limiter *rateLimiter, headers map[string]string, ct ContentType, u, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader = f
+	if limiter != nil {
+		body = &rateLimitedReader{ctx: ctx, r: f, limiter: limiter}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = fi.Size()
+	req.Header.Set("Content-Type", ct.Type)
+	if ct.CacheControl != "" {
+		req.Header.Set("Cache-Control", ct.CacheControl)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
+
+// isBookkeepingFile returns whether the file at path is one of the files
+// dojoBuilder keeps in destDir rather than a released file: the lock file,
+// the metadata sidecar and the version, versions, cache-busting and canary
+// files of the build configs.
+func isBookkeepingFile(destDir, path string) bool {
+	name := filepath.Base(path)
+	if name == LockFileName || name == MetadataFileName {
+		return true
+	}
+
+	if filepath.Dir(path) != filepath.Clean(destDir) || !strings.HasPrefix(name, "dojoBuilder.") {
+		return false
+	}
+
+	switch filepath.Ext(name) {
+	case ".version", ".versions", ".cachebust", ".canary":
+		return true
+	}
+
+	return false
+}
+
+// rateLimiter spreads the bytes transferred over time so that their rate
+// stays below its limit, in bytes/s. It is shared by the successive uploads.
+type rateLimiter struct {
+	rate  float64
+	start time.Time
+	n     int64
+}
+
+// newRateLimiter returns the limiter of rate bytes/s, nil if rate is not
+// positive
+func newRateLimiter(rate int64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{rate: float64(rate)}
+}
+
+// wait accounts n bytes more, waiting until they are within the rate or ctx
+// is done
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l.start.IsZero() {
+		l.start = time.Now()
+	}
+	l.n += int64(n)
+
+	due := l.start.Add(time.Duration(float64(l.n) / l.rate * float64(time.Second)))
+	d := time.Until(due)
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitedReader reads r at the rate of its limiter
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	// Small reads keep the rate steady
+	if max := int(r.limiter.rate); len(p) > max && max > 0 {
+		p = p[:max]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.wait(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err
+}
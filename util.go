@@ -1,10 +1,17 @@
 package dojoBuilder
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
+
+	"github.com/tbaud0n/dojoBuilder/copyutil"
 )
 
 func IsMatchSliceMember(slice []string, st string) (bool, error) {
@@ -20,120 +27,96 @@ func IsMatchSliceMember(slice []string, st string) (bool, error) {
 	return false, nil
 }
 
-func CopyDir(src string, dest string) (err error) {
+// CopyDir copies the directory src into dest, see copyutil.CopyDir
+func CopyDir(src string, dest string) error {
+	return copyutil.CopyDir(src, dest)
+}
 
-	sfi, err := os.Lstat(src)
-	if err != nil {
-		return err
+// CopyFile copies the file src to dest, see copyutil.CopyFile
+func CopyFile(src, dest string) error {
+	return copyutil.CopyFile(src, dest)
+}
+
+// urlPath percent-encodes the segments of the slash separated path p, so
+// that non-ASCII file names can be used in urls and HTTP headers
+func urlPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
 	}
 
-	if !sfi.IsDir() {
-		return fmt.Errorf("CopyFile cannot copy a file %s", src)
-	} else if sfi.Mode()&os.ModeSymlink != 0 {
-		linkSrc, err := os.Readlink(src)
-		if err != nil {
-			return err
+	return strings.Join(segments, "/")
+}
+
+// containsString reports whether s is an element of slice
+func containsString(slice []string, s string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
 		}
-		return CopyDir(linkSrc, dest)
 	}
 
-	err = os.MkdirAll(dest, sfi.Mode())
+	return false
+}
+
+// destPath returns the path in the tree destRoot of path, a path of the tree
+// srcRoot. It fails if path is not within srcRoot so that the result never
+// escapes destRoot, whatever the separators or trailing slashes of the roots.
+func destPath(srcRoot, destRoot, path string) (string, error) {
+	rel, err := filepath.Rel(srcRoot, path)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	directory, _ := os.Open(src)
-
-	objects, err := directory.Readdir(-1)
-
-	for _, obj := range objects {
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not within %s", path, srcRoot)
+	}
 
-		srcfilepointer := src + "/" + obj.Name()
+	return filepath.Join(destRoot, rel), nil
+}
 
-		destfilepointer := dest + "/" + obj.Name()
+// readLines calls f with every line read from r, without its line ending,
+// whatever its length. It returns the read error, if any.
+func readLines(r io.Reader, f func(line string)) error {
+	br := bufio.NewReader(r)
 
-		if obj.IsDir() {
-			CopyDir(srcfilepointer, destfilepointer)
-			if err != nil {
-				return
-			}
-		} else {
-			err = CopyFile(srcfilepointer, destfilepointer)
-			if err != nil {
-				return
-			}
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			f(strings.TrimRight(line, "\r\n"))
 		}
 
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
 	}
-	return
 }
 
-// CopyFile copies a file from src to dest. If src and dest files exist, and are
-// the same, then return success. Otherise, attempt to create a hard link
-// between the two files. If that fail, copy the file contents from src to dest.
-func CopyFile(src, dest string) (err error) {
-	sfi, err := os.Lstat(src)
+// writeFileAtomic writes b into the file at path through a temporary file of
+// its dir renamed over it, so that the readers of path see either its old or
+// its new content
+func writeFileAtomic(path string, b []byte, perm os.FileMode) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
 	if err != nil {
-		return
-	}
-
-	if !sfi.Mode().IsRegular() {
-		if sfi.IsDir() {
-			return fmt.Errorf("CopyFile cannot copy a directory %s", src)
-		} else if sfi.Mode()&os.ModeSymlink != 0 {
-			linkSrc, err := os.Readlink(src)
-			if err != nil {
-				return err
-			}
-			return CopyFile(linkSrc, dest)
-		} else {
-			return fmt.Errorf("CopyFile: non-regular source file %s (%q)", src, sfi.Mode().String())
-		}
+		return err
 	}
+	tmp := f.Name()
 
-	dfi, err := os.Lstat(dest)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return
-		}
-	} else {
-		if !(dfi.Mode().IsRegular()) {
-			return fmt.Errorf("CopyFile: non-regular destination file %s (%q)", dfi.Name(), dfi.Mode().String())
-		}
-		if os.SameFile(sfi, dfi) {
-			return
-		}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
 	}
-	if err = os.Link(src, dest); err == nil {
-		return
+	if err == nil {
+		err = os.Chmod(tmp, perm)
 	}
-	err = copyFileContents(src, dest)
-	return
-}
-
-// copyFileContents copies the contents of the file named src to the file named
-// by dest. The file will be created if it does not already exist. If the
-// destination file exists, all it's contents will be replaced by the contents
-// of the source file.
-func copyFileContents(src, dest string) (err error) {
-	in, err := os.Open(src)
-	if err != nil {
-		return
+	if err == nil {
+		err = os.Rename(tmp, path)
 	}
-	defer in.Close()
-	out, err := os.Create(dest)
 	if err != nil {
-		return
-	}
-	defer func() {
-		cerr := out.Close()
-		if err == nil {
-			err = cerr
-		}
-	}()
-	if _, err = io.Copy(out, in); err != nil {
-		return
+		os.Remove(tmp)
 	}
-	err = out.Sync()
-	return
+
+	return err
 }
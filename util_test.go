@@ -0,0 +1,123 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDestPath(t *testing.T) {
+	long := strings.Repeat(strings.Repeat("d", 100)+"/", 40) + "module.js"
+
+	tests := []struct {
+		name     string
+		srcRoot  string
+		destRoot string
+		path     string
+		want     string
+		fails    bool
+	}{
+		{"nested file", "/src", "/dest", "/src/app/main.js", "/dest/app/main.js", false},
+		{"root", "/src", "/dest", "/src", "/dest", false},
+		{"trailing slashes", "/src/", "/dest/", "/src/app/", "/dest/app", false},
+		{"dot dot traversal", "/src", "/dest", "/src/../etc/passwd", "", true},
+		{"dot dot inside", "/src", "/dest", "/src/app/../lib/x.js", "/dest/lib/x.js", false},
+		{"parent", "/src", "/dest", "/", "", true},
+		{"sibling with the root prefix", "/src", "/dest", "/src-other/x.js", "", true},
+		{"dot dot prefixed name", "/src", "/dest", "/src/..app/x.js", "/dest/..app/x.js", false},
+		{"unicode names", "/src", "/dest", "/src/été/日本語 ü.js", "/dest/été/日本語 ü.js", false},
+		{"unicode roots", "/sôurce", "/déstination", "/sôurce/app/ñ.js", "/déstination/app/ñ.js", false},
+		{"long path", "/src", "/dest", "/src/" + long, "/dest/" + long, false},
+		{"relative path", "/src", "/dest", "app/main.js", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := destPath(filepath.FromSlash(tt.srcRoot), filepath.FromSlash(tt.destRoot), filepath.FromSlash(tt.path))
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("destPath(%q) = %q, want an error", tt.path, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("destPath(%q): %s", tt.path, err)
+			} else if want := filepath.FromSlash(tt.want); got != want {
+				t.Fatalf("destPath(%q) = %q, want %q", tt.path, got, want)
+			}
+		})
+	}
+}
+
+func TestURLPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"app/main.js", "app/main.js"},
+		{"app/été/日本.js", "app/%C3%A9t%C3%A9/%E6%97%A5%E6%9C%AC.js"},
+		{"nls/fr/ü file.js", "nls/fr/%C3%BC%20file.js"},
+		{"a?b/c#d.js", "a%3Fb/c%23d.js"},
+		{"a%b.js", "a%25b.js"},
+		{"/abs/path/", "/abs/path/"},
+	}
+
+	for _, tt := range tests {
+		if got := urlPath(tt.path); got != tt.want {
+			t.Errorf("urlPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"lines", "a\nb\n", []string{"a", "b"}},
+		{"no final line ending", "a\nb", []string{"a", "b"}},
+		{"crlf", "a\r\nb\r\n", []string{"a", "b"}},
+		{"empty lines", "\n\na\n", []string{"", "", "a"}},
+		{"long line", "a\n" + long + "\nb\n", []string{"a", long, "b"}},
+		{"long last line", long, []string{long}},
+		{"non-ASCII", "été\n日本語\n", []string{"été", "日本語"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			if err := readLines(strings.NewReader(tt.input), func(line string) {
+				got = append(got, line)
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("line %d has %d bytes, want %d", i, len(got[i]), len(tt.want[i]))
+				}
+			}
+		})
+	}
+
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a\npartial"), iotest.ErrReader(errRead))
+
+	var got []string
+	if err := readLines(r, func(line string) { got = append(got, line) }); err != errRead {
+		t.Fatalf("readLines returned %v, want %v", err, errRead)
+	} else if len(got) != 2 || got[1] != "partial" {
+		t.Fatalf("readLines read %q before the error", got)
+	}
+}
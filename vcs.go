@@ -0,0 +1,98 @@
+package dojoBuilder
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// VCSInfo describes the revision of the sources a build is made from
+type VCSInfo struct {
+	Version string `json:"version,omitempty"` // Human readable version, e.g. "v1.2.0-3-gabcdef0"
+	Commit  string `json:"commit,omitempty"`  // Commit, changeset or revision id
+	Branch  string `json:"branch,omitempty"`  // Branch, "" if unknown or detached
+}
+
+// VCSProvider returns the revision of the sources of the directory dir. It
+// lets the Mercurial or SVN repositories, or the CI environment, supply the
+// revision of the builds, see Config.VCS.
+type VCSProvider interface {
+	Info(dir string) (VCSInfo, error)
+}
+
+// GitVCS is the VCSProvider of the git repositories, using the git command
+type GitVCS struct{}
+
+func (GitVCS) Info(dir string) (i VCSInfo, err error) {
+	if i.Commit, err = git(dir, "rev-parse", "HEAD"); err != nil {
+		return
+	}
+
+	if i.Version, err = git(dir, "describe", "--tags", "--always", "--dirty"); err != nil {
+		return
+	}
+
+	if b, berr := git(dir, "rev-parse", "--abbrev-ref", "HEAD"); berr == nil && b != "HEAD" {
+		i.Branch = b
+	}
+
+	return
+}
+
+// EnvVCS is the VCSProvider reading the revision from environment variables,
+// e.g. the ones set by the CI. Unset variables leave their field empty.
+type EnvVCS struct {
+	VersionEnv string // e.g. "CI_COMMIT_TAG"
+	CommitEnv  string // e.g. "CI_COMMIT_SHA"
+	BranchEnv  string // e.g. "CI_COMMIT_BRANCH"
+}
+
+func (e EnvVCS) Info(dir string) (i VCSInfo, err error) {
+	for _, f := range []struct {
+		env   string
+		value *string
+	}{{e.VersionEnv, &i.Version}, {e.CommitEnv, &i.Commit}, {e.BranchEnv, &i.Branch}} {
+		if f.env != "" {
+			*f.value = os.Getenv(f.env)
+		}
+	}
+
+	if i.Commit == "" && i.Version == "" {
+		err = errors.New("No revision in the environment")
+	}
+
+	return
+}
+
+// vcsInfo returns the revision of the SrcDir of c from its VCS, a zero
+// VCSInfo if unknown: the sources need not be versioned.
+func (c *Config) vcsInfo() VCSInfo {
+	var p VCSProvider = GitVCS{}
+	if c.VCS != nil {
+		p = c.VCS
+	}
+
+	i, err := p.Info(c.SrcDir)
+	if err != nil {
+		return VCSInfo{}
+	}
+
+	return i
+}
+
+// vcsReleaseName returns version, the name of a versioned release, prefixed
+// with the VCS version of c when known
+func (c *Config) vcsReleaseName(version string) string {
+	v := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, c.revision.Version)
+
+	if v == "" {
+		return version
+	}
+
+	return v + "-" + version
+}
@@ -0,0 +1,94 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// vendorPackages are the toolkit packages whose modules go to the
+// VendorLayer
+var vendorPackages = map[string]bool{"dojo": true, "dijit": true, "dojox": true}
+
+// isVendorModule reports whether mid is a module of the vendorPackages
+func isVendorModule(mid string) bool {
+	return vendorPackages[strings.SplitN(mid, "/", 2)[0]]
+}
+
+// vendorModules returns the toolkit modules the layers of bc, but the
+// VendorLayer and the toolkit ones, depend on, directly or through the
+// application modules. The dependencies of the toolkit modules are left to
+// the dojo builder.
+func (c *Config) vendorModules(bc BuildConfig) (modules []string) {
+	res := NewResolver(c.SrcDir, c.sourcePackages(bc.Packages), bc.Map).WithAliases(bc.Aliases)
+
+	var queue []string
+	for mid, l := range bc.Layers {
+		if mid != bc.VendorLayer && !isVendorModule(mid) {
+			queue = append(queue, mid)
+			queue = append(queue, l.Include...)
+		}
+	}
+
+	seen := make(map[string]bool)
+
+	for len(queue) > 0 {
+		mid := queue[0]
+		queue = queue[1:]
+
+		if seen[mid] {
+			continue
+		}
+		seen[mid] = true
+
+		if isVendorModule(mid) {
+			modules = append(modules, mid)
+			continue
+		}
+
+		// require, exports, module and the modules the build generates
+		p, err := res.Path(mid)
+		if err != nil {
+			continue
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			continue
+		}
+
+		queue = append(queue, moduleDependencies(mid, b)...)
+	}
+
+	sort.Strings(modules)
+
+	return
+}
+
+// applyVendorLayer includes into the VendorLayer of bc the toolkit modules
+// of its other layers and excludes them from these layers
+func (c *Config) applyVendorLayer(bc *BuildConfig) error {
+	if _, ok := bc.Layers[bc.VendorLayer]; !ok {
+		return fmt.Errorf("The vendor layer '%s' is not a layer", bc.VendorLayer)
+	}
+
+	modules := c.vendorModules(*bc)
+	if len(modules) == 0 {
+		fmt.Fprintf(c.stdout(), "Warning: the layers use no toolkit module, the vendor layer %s is empty\n", bc.VendorLayer)
+	}
+
+	layers := make(map[string]Layer, len(bc.Layers))
+
+	for mid, l := range bc.Layers {
+		if mid == bc.VendorLayer {
+			l.Include = append(append([]string(nil), l.Include...), modules...)
+		} else if !isVendorModule(mid) {
+			l.Exclude = append(append([]string(nil), l.Exclude...), modules...)
+		}
+		layers[mid] = l
+	}
+
+	bc.Layers = layers
+
+	return nil
+}
@@ -0,0 +1,395 @@
+package dojoBuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is the default interval between two checks of a Watcher
+const DefaultWatchInterval = time.Second
+
+// Watcher rebuilds build configs whenever the sources change. When the config
+// was loaded from a file, changes to the file are validated and applied
+// without restarting. The Schedules of the config run builds periodically,
+// after the build in progress if any: the times missed meanwhile are
+// skipped. The build excludes can be changed by the config file
+// or SetBuildExcludes, the exclude funcs by RegisterExcludeFunc: they are
+// resolved again by every build.
+type Watcher struct {
+	Config     *Config
+	ConfigFile string          // Path of the config file Config was loaded from (optional)
+	Names      []string        // Build configs to build (optional, default all)
+	Interval   time.Duration   // Interval between two checks (optional, default DefaultWatchInterval)
+	Notifier   Notifier        // Notified of every build outcome (optional)
+	Reloader   Reloader        // Refreshes the browser after every successful build (optional)
+	Cancel     <-chan struct{} // Closed to cancel the build in progress, which returns ErrBuildCanceled (optional)
+
+	sources    map[string]time.Time
+	configTime time.Time
+	schedules  []scheduledBuild
+
+	mu      sync.Mutex
+	lastErr error
+	rebuild bool // Rebuild on the next check even if the sources didn't change
+}
+
+// SetBuildExcludes replaces the Config.BuildExcludes of the running watcher
+// and rebuilds with them on the next check
+func (w *Watcher) SetBuildExcludes(names []string) error {
+	if err := checkExcludeFuncs(names); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.Config.BuildExcludes = names
+	w.rebuild = true
+
+	return nil
+}
+
+// LastError returns the error of the last build, nil if it succeeded
+func (w *Watcher) LastError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.lastErr
+}
+
+// Run builds, then rebuilds on every change until stop is closed. Build
+// errors are printed and do not stop the watcher. A build in progress when
+// stop is closed is finished, unless Cancel is closed too.
+func (w *Watcher) Run(stop <-chan struct{}) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	if w.ConfigFile != "" {
+		if fi, err := os.Stat(w.ConfigFile); err == nil {
+			w.configTime = fi.ModTime()
+		}
+	}
+
+	var err error
+	if w.sources, err = w.Config.sourceTimes(); err != nil {
+		return err
+	}
+
+	if w.schedules, err = newScheduledBuilds(w.Config.Schedules, time.Now()); err != nil {
+		return err
+	}
+
+	w.build(w.Names, false, "")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-w.Cancel:
+			return nil
+		case <-ticker.C:
+		}
+
+		rebuild := w.reloadConfig()
+
+		w.mu.Lock()
+		rebuild = rebuild || w.rebuild
+		w.rebuild = false
+		w.mu.Unlock()
+
+		sources, err := w.Config.sourceTimes()
+		if err != nil {
+			fmt.Fprintf(w.Config.stdout(), "Cannot scan sources: %s\n", err)
+			continue
+		}
+
+		if !reflect.DeepEqual(sources, w.sources) {
+			w.sources = sources
+			rebuild = true
+		}
+
+		if rebuild {
+			w.build(w.Names, false, "")
+		}
+
+		w.runSchedules()
+	}
+}
+
+// scheduledBuild is a Schedule with the time of its next run
+type scheduledBuild struct {
+	Schedule
+	cron cronExpr
+	next time.Time
+}
+
+func newScheduledBuilds(schedules []Schedule, now time.Time) ([]scheduledBuild, error) {
+	sbs := make([]scheduledBuild, len(schedules))
+
+	for i, s := range schedules {
+		e, err := parseCron(s.Cron)
+		if err != nil {
+			return nil, err
+		}
+
+		sbs[i] = scheduledBuild{Schedule: s, cron: e, next: e.next(now)}
+		if sbs[i].next.IsZero() {
+			return nil, fmt.Errorf("Cron expression '%s' never matches", s.Cron)
+		}
+	}
+
+	return sbs, nil
+}
+
+// runSchedules runs the scheduled builds which are due
+func (w *Watcher) runSchedules() {
+	for i := range w.schedules {
+		s := &w.schedules[i]
+		if s.next.IsZero() || time.Now().Before(s.next) {
+			continue
+		}
+
+		names := s.Builds
+		if len(names) == 0 {
+			names = w.Names
+		}
+
+		fmt.Fprintf(w.Config.stdout(), "Scheduled build (%s)\n", s.Cron)
+		w.build(names, s.Reset, "scheduled ")
+
+		// The builds overlapping the next times don't catch up
+		now := time.Now()
+		if missed := s.cron.next(s.next); !missed.IsZero() && missed.Before(now) {
+			fmt.Fprintf(w.Config.stdout(), "Skipped the scheduled builds (%s) missed since %s\n", s.Cron, missed.Format(time.RFC3339))
+		}
+		s.next = s.cron.next(now)
+	}
+}
+
+// build builds names, emptying DestDir first if reset. kind prefixes the
+// notification title.
+func (w *Watcher) build(names []string, reset bool, kind string) {
+	start := time.Now()
+
+	w.mu.Lock()
+	c := *w.Config
+	w.mu.Unlock()
+
+	c.cancel = w.Cancel
+
+	var err error
+	if reset {
+		var unlock func()
+		if unlock, err = c.lockDestDir(); err == nil {
+			defer unlock()
+			c.locked = true
+			c.resetDestDir()
+		}
+	}
+
+	if err == nil {
+		_, err = c.Build(names)
+	}
+
+	w.mu.Lock()
+	w.lastErr = err
+	w.mu.Unlock()
+
+	if err == ErrBuildCanceled {
+		fmt.Fprintln(w.Config.stdout(), err)
+		return
+	} else if err != nil {
+		fmt.Fprintf(w.Config.stdout(), "Build failed: %s\n", err)
+	} else if w.Reloader != nil {
+		if rerr := w.Reloader.Reload(); rerr != nil {
+			fmt.Fprintf(w.Config.stdout(), "Cannot reload the browser: %s\n", rerr)
+		}
+	}
+
+	if w.Notifier == nil {
+		return
+	}
+
+	title, message := "dojoBuilder "+kind+"build succeeded", fmt.Sprintf("Built in %s", time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		title, message = "dojoBuilder "+kind+"build failed", err.Error()
+	}
+
+	if nerr := w.Notifier.Notify(title, message, err != nil); nerr != nil {
+		fmt.Fprintf(w.Config.stdout(), "Cannot send notification: %s\n", nerr)
+	}
+}
+
+// reloadConfig applies the BuildConfigs, BuildExcludes and Schedules of the
+// config file if it changed and is valid. It returns true if the build
+// configs or excludes were changed.
+func (w *Watcher) reloadConfig() bool {
+	if w.ConfigFile == "" {
+		return false
+	}
+
+	fi, err := os.Stat(w.ConfigFile)
+	if err != nil || fi.ModTime().Equal(w.configTime) {
+		return false
+	}
+	w.configTime = fi.ModTime()
+
+	nc, err := LoadConfigFile(w.ConfigFile)
+	if err != nil {
+		fmt.Fprintf(w.Config.stdout(), "Cannot reload %s: %s\n", w.ConfigFile, err)
+		return false
+	}
+
+	for name, bc := range nc.BuildConfigs {
+		if err = validatePackages(bc); err == nil {
+			err = validateTransforms(bc)
+		}
+		if err != nil {
+			fmt.Fprintf(w.Config.stdout(), "Ignoring %s, build config %s is invalid: %s\n", w.ConfigFile, name, err)
+			return false
+		}
+	}
+
+	if err = checkExcludeFuncs(nc.BuildExcludes); err != nil {
+		fmt.Fprintf(w.Config.stdout(), "Ignoring %s, buildExcludes are invalid: %s\n", w.ConfigFile, err)
+		return false
+	}
+
+	if !reflect.DeepEqual(w.Config.Schedules, nc.Schedules) {
+		schedules, err := newScheduledBuilds(nc.Schedules, time.Now())
+		if err != nil {
+			fmt.Fprintf(w.Config.stdout(), "Ignoring %s, schedules are invalid: %s\n", w.ConfigFile, err)
+			return false
+		}
+
+		fmt.Fprintf(w.Config.stdout(), "Reloaded the schedules of %s\n", w.ConfigFile)
+		w.schedules = schedules
+		w.mu.Lock()
+		w.Config.Schedules = nc.Schedules
+		w.mu.Unlock()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	diff := diffBuildConfigs(w.Config.BuildConfigs, nc.BuildConfigs)
+	if strings.Join(w.Config.BuildExcludes, ",") != strings.Join(nc.BuildExcludes, ",") {
+		diff = append(diff, "~ buildExcludes ("+strings.Join(nc.BuildExcludes, ", ")+")")
+	}
+
+	if len(diff) == 0 {
+		return false
+	}
+
+	fmt.Fprintf(w.Config.stdout(), "Reloaded %s:\n  %s\n", w.ConfigFile, strings.Join(diff, "\n  "))
+
+	w.Config.BuildConfigs = nc.BuildConfigs
+	w.Config.BuildExcludes = nc.BuildExcludes
+
+	return true
+}
+
+// sourceTimes returns the modification time of the files of SrcDir and of
+// the external packages, but the generated profiles and DestDir
+func (c *Config) sourceTimes() (times map[string]time.Time, err error) {
+	times = make(map[string]time.Time)
+
+	profiles := c.profilesDir()
+
+	walk := func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if f.IsDir() {
+			if path == profiles || path == c.DestDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		times[path] = f.ModTime()
+
+		return nil
+	}
+
+	if err = filepath.Walk(c.SrcDir, walk); err != nil {
+		return
+	}
+
+	for _, bc := range c.BuildConfigs {
+		for _, p := range bc.Packages {
+			if p.External() {
+				if err = filepath.Walk(p.Dir(c.SrcDir), walk); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// diffBuildConfigs describes the build configs added, removed and changed
+// (with the changed profile settings) between old and new
+func diffBuildConfigs(old, new map[string]BuildConfig) (diff []string) {
+	for name, nbc := range new {
+		obc, ok := old[name]
+		if !ok {
+			diff = append(diff, "+ "+name)
+			continue
+		}
+
+		if fields := changedFields(obc, nbc); len(fields) > 0 {
+			diff = append(diff, "~ "+name+" ("+strings.Join(fields, ", ")+")")
+		}
+	}
+
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			diff = append(diff, "- "+name)
+		}
+	}
+
+	sort.Slice(diff, func(i, j int) bool { return diff[i][2:] < diff[j][2:] })
+
+	return
+}
+
+// changedFields returns the JSON names of the fields differing between a and b
+func changedFields(a, b BuildConfig) (fields []string) {
+	var am, bm map[string]json.RawMessage
+
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	json.Unmarshal(ab, &am)
+	json.Unmarshal(bb, &bm)
+
+	for k, v := range bm {
+		if string(am[k]) != string(v) {
+			fields = append(fields, k)
+		}
+	}
+
+	for k := range am {
+		if _, ok := bm[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+
+	sort.Strings(fields)
+
+	return
+}
@@ -0,0 +1,74 @@
+package dojoBuilder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const defaultWebhookTimeout = 30 * time.Second
+
+// WebhookDeployer deploys by posting the build to a deploy service, e.g. the
+// hook of a CD pipeline or of a CDN purge, which fetches the release itself.
+// The body of the request is the JSON WebhookPayload of the build.
+type WebhookDeployer struct {
+	URL           string            // Url the payload is posted to
+	HeaderSecrets map[string]string // Request header => name of the secret it is set to, e.g. "Authorization" (optional)
+	Timeout       time.Duration     // Timeout of the request (optional, default 30s)
+}
+
+// WebhookPayload is the body of the WebhookDeployer requests
+type WebhookPayload struct {
+	Name    string `json:"name"` // Build config name
+	BuildID string `json:"buildId"`
+	Commit  string `json:"commit,omitempty"` // Commit built, if known, see Config.VCS
+	Branch  string `json:"branch,omitempty"` // VCS branch built, if known
+}
+
+func (d WebhookDeployer) Deploy(c *Config, name string) error {
+	headers, err := c.secretValues(d.HeaderSecrets)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(WebhookPayload{Name: name, BuildID: c.buildID, Commit: c.revision.Commit, Branch: c.revision.Branch})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	client, err := c.httpClient(timeout)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned status %d: %s", d.URL, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package dojoBuilder
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// workName returns the name of the intermediate file or directory base of
+// the build config name (may be empty), namespaced by the build id so that
+// concurrent builds don't collide
+func (c *Config) workName(base, name string) string {
+	if name != "" {
+		base += "-" + name
+	}
+
+	if c.buildID != "" {
+		base += "-" + c.buildID
+	}
+
+	return base
+}
+
+// workPath returns the path of workName(base, name) in dir
+func (c *Config) workPath(dir, base, name string) string {
+	return filepath.Join(dir, c.workName(base, name))
+}
+
+// releaseDir returns the directory the build config name is released into
+// before being copied into DestDir
+func (c *Config) releaseDir(name string) string {
+	return c.workPath(c.DestDir, "dojoBuilderTMP", name)
+}
+
+// profilesDir returns the directory of the generated profiles: SrcDir/profiles,
+// or a directory of DestDir when SrcDir is read-only
+func (c *Config) profilesDir() string {
+	if c.ReadOnlySrc {
+		return c.workPath(c.DestDir, "dojoBuilderPROFILES", "")
+	}
+
+	return filepath.Join(c.SrcDir, "profiles")
+}
+
+// removeProfile removes the profile of the build config name generated at
+// profilePath, with its stub package
+func (c *Config) removeProfile(name, profilePath string) {
+	os.Remove(profilePath)
+	os.RemoveAll(filepath.Join(c.profilesDir(), c.workName(stubsPackageName, name)))
+
+	if c.ReadOnlySrc {
+		// Only removed once the profiles of the other build configs are
+		os.Remove(c.profilesDir())
+	}
+}
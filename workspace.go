@@ -0,0 +1,104 @@
+package dojoBuilder
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ToolkitDirs are the directories of a dojo checkout shared by a Workspace
+var ToolkitDirs = []string{"dojo", "dijit", "dojox", "util"}
+
+// Workspace manages several applications sharing a single dojo checkout and
+// build cache
+type Workspace struct {
+	DojoDir  string                   // Absolute path of the shared dojo checkout, the DojoDir of the applications having none (optional)
+	CacheDir string                   // Absolute path of the shared build cache, the CacheDir of the applications having none (optional)
+	Apps     map[string]*WorkspaceApp // Applications by name
+}
+
+type WorkspaceApp struct {
+	Config    *Config
+	Names     []string // Build config names to run (all if empty)
+	DependsOn []string // Applications whose output is an input of this one
+}
+
+// Run runs every application of the workspace, dependencies first
+func (w *Workspace) Run(reset bool) (err error) {
+	order, err := w.BuildOrder()
+	if err != nil {
+		return
+	}
+
+	for _, name := range order {
+		app := w.Apps[name]
+
+		ac := *app.Config
+		if ac.DojoDir == "" {
+			ac.DojoDir = w.DojoDir
+		}
+		if ac.CacheDir == "" {
+			ac.CacheDir = w.CacheDir
+		}
+
+		fmt.Fprintf(ac.stdout(), "Running %s application\n", name)
+
+		if err = Run(&ac, app.Names, reset); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	return
+}
+
+// BuildOrder returns the application names sorted so that every application
+// comes after the ones it depends on.
+func (w *Workspace) BuildOrder() (order []string, err error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(w.Apps))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		app, ok := w.Apps[name]
+		if !ok {
+			return fmt.Errorf("Unknown workspace application '%s'", name)
+		}
+
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("Dependency cycle detected on application '%s'", name)
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, d := range app.DependsOn {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		order = append(order, name)
+
+		return nil
+	}
+
+	names := make([]string, 0, len(w.Apps))
+	for n := range w.Apps {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		if err = visit(n); err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
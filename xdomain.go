@@ -0,0 +1,28 @@
+package dojoBuilder
+
+import (
+	"os"
+	"strings"
+)
+
+// XDomainLoader is the Loader of the cross-domain builds: the dojo builder
+// of the legacy (dojo < 1.7) modules outputs a .xd.js version of each module
+// the xdomain loader can load from another domain, e.g. a CDN.
+const XDomainLoader = "xdomain"
+
+// xdomainPath returns the path of the cross-domain version of the js file p
+func xdomainPath(p string) string {
+	return strings.TrimSuffix(p, ".js") + ".xd.js"
+}
+
+// isXDomainReplaced reports whether the js file p has a cross-domain
+// version, which is the one released by XDomainOnly
+func isXDomainReplaced(p string) bool {
+	if !strings.HasSuffix(p, ".js") || strings.HasSuffix(p, ".xd.js") {
+		return false
+	}
+
+	_, err := os.Stat(xdomainPath(p))
+
+	return err == nil
+}